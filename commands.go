@@ -0,0 +1,578 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"estimatorium/core"
+	"estimatorium/lsp"
+	"estimatorium/server"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const stdio = "-"
+
+// inputFormat gives the format of the project by the extension of the file unless it's set
+func inputFormat(path, format string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		return server.FormatYaml
+	case ".json":
+		return server.FormatJson
+	default:
+		return server.FormatDsl
+	}
+}
+
+func readInput(path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == stdio {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, ioError{err}
+	}
+	return data, nil
+}
+
+// inputOptions are the flags of the commands reading projects
+type inputOptions struct {
+	format      *string
+	rateCard    *string
+	includeTags *string
+	excludeTags *string
+}
+
+func addInputFlags(flags *flag.FlagSet) inputOptions {
+	return inputOptions{
+		format:   flags.String("input-format", "", "dsl, yaml or json, by the extension if not set"),
+		rateCard: flags.String("rate-card", "", "the rate card `file` to take the rates from instead of the one of the rate_card directive"),
+
+		includeTags: flags.String("include-tags", "", "keep only the tasks having any of the comma-separated `tags`"),
+		excludeTags: flags.String("exclude-tags", "", "drop the tasks having any of the comma-separated `tags`"),
+	}
+}
+
+// readProject parses the project from the file or stdin, it returns the files the project is read from
+func readProject(path string, in inputOptions) (core.Project, []string, error) {
+	var card *core.RateCard
+	if *in.rateCard != "" {
+		// the card of the rate_card directive is not read then, it may be expired
+		fileCard, err := core.RateCardFromFile(*in.rateCard)
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			return core.Project{}, []string{path, *in.rateCard}, ioError{err}
+		} else if err != nil {
+			return core.Project{}, []string{path, *in.rateCard}, err
+		}
+		card = &fileCard
+	}
+	project, files, err := parseProject(path, *in.format, card)
+	if *in.rateCard != "" {
+		files = append(files, *in.rateCard)
+	}
+	if err != nil {
+		return project, files, err
+	}
+	if *in.includeTags != "" || *in.excludeTags != "" {
+		// before anything is calculated or generated
+		project, err = project.WithTags(core.ParseTags(*in.includeTags), core.ParseTags(*in.excludeTags))
+	}
+	return project, files, err
+}
+
+// parseProject reads the project from the file along with the files it refers to, the card replaces the one of the project
+func parseProject(path, format string, card *core.RateCard) (core.Project, []string, error) {
+	files := []string{path}
+	data, err := readInput(path)
+	if err != nil {
+		return core.Project{}, files, err
+	}
+	format = inputFormat(path, format)
+	if format != server.FormatYaml && format != server.FormatJson && format != server.FormatDsl {
+		return core.Project{}, files, usageErrorf("unknown input format: %s", format)
+	}
+	if path != stdio {
+		return core.ProjectFromFileTextWith(path, string(data), core.ProjectFileOptions{Format: format, RateCard: card})
+	}
+	var project core.Project
+	switch format {
+	case server.FormatYaml:
+		project, err = core.ProjectFromYaml(string(data))
+	case server.FormatJson:
+		project, err = core.ProjectFromJson(string(data))
+	default:
+		project, err = core.ProjectFromString(string(data))
+	}
+	if err == nil && card != nil {
+		project, err = project.WithRateCard(*card, time.Now())
+	}
+	return project, files, err
+}
+
+// writeOutput renders the output in memory first, so that the problems of the project don't leave broken files
+func writeOutput(path string, render func(w io.Writer) error) error {
+	var buf bytes.Buffer
+	if err := render(&buf); err != nil {
+		return err
+	}
+	var err error
+	if path == stdio {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = os.WriteFile(path, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		return ioError{err}
+	}
+	return nil
+}
+
+// outputFormat is the extension of the file without the dot, or format for stdout
+func outputFormat(path, format string) string {
+	if path == stdio {
+		return format
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// renderOutput writes the project in the format: the model for xlsx and ods, the project itself for yml and json,
+// the report of the result for md and html
+func renderOutput(format string, project core.Project, result core.ProjectCalculationResult, opts core.ExcelOptions) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		var str string
+		var err error
+		switch format {
+		case "xlsx":
+			return core.GenerateExcelTo(w, project, opts)
+		case "ods":
+			return core.GenerateOdsTo(w, project, opts)
+		case "yml", "yaml":
+			str, err = core.ProjectToYaml(project)
+		case "json":
+			str, err = core.ProjectToJson(project)
+		case "md":
+			return core.WriteMarkdown(w, project, result)
+		case "html":
+			return core.WriteHtml(w, project, result)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, str)
+		return err
+	}
+}
+
+var outputFormats = map[string]bool{"xlsx": true, "ods": true, "yml": true, "yaml": true, "json": true, "md": true, "html": true}
+
+func runGenerate(args []string) error {
+	flags := newFlagSet("generate", "proj.txt report.xlsx|report.ods|proj.yml|proj.json ...",
+		"Write the model and other outputs, print the summary.")
+	opts := core.ExcelOptions{}
+	flags.BoolVar(&opts.Compatible, "compat", false, "formulas compatible with Excel 2013-2016, older LibreOffice")
+	flags.StringVar(&opts.SheetName, "sheet", "", "name of the sheet with the model")
+	flags.Float64Var(&opts.SensitivityRange, "range", core.DefaultSensitivityRange, "relative variation of the inputs on the Sensitivity sheet")
+	watch := flags.Bool("watch", false, "regenerate on every change of the project, Ctrl+C to stop")
+	format := flags.String("format", "xlsx", "format of the output to stdout: xlsx, ods, yml, json, md or html")
+	in := addInputFlags(flags)
+	quiet := flags.Bool("q", false, "don't print the summary")
+	positional, err := parseArgs(flags, args, 2, -1)
+	if err != nil {
+		return err
+	}
+	if opts.SensitivityRange <= 0 {
+		return usageErrorf("-range must be positive")
+	}
+	input, outputs := positional[0], positional[1:]
+	toStdout := false
+	for _, output := range outputs {
+		if !outputFormats[outputFormat(output, *format)] {
+			return usageErrorf("unknown output format of %s, use .xlsx, .ods, .yml, .json, .md or .html", output)
+		}
+		toStdout = toStdout || output == stdio
+	}
+	if *watch && input == stdio {
+		return usageErrorf("can't watch stdin")
+	}
+	// stdout may carry the output itself
+	summary := os.Stdout
+	if toStdout {
+		summary = os.Stderr
+	}
+
+	generate := func() ([]string, error) {
+		project, files, err := readProject(input, in)
+		if err != nil {
+			return files, err
+		}
+		// once for all the outputs, the team is sized for desired_duration by it
+		result := project.Calculate()
+		for _, output := range outputs {
+			if err := writeOutput(output, renderOutput(outputFormat(output, *format), project, result, opts)); err != nil {
+				return files, err
+			}
+		}
+		if !*quiet {
+			if err := writeCalculation(summary, project, result, false, 0); err != nil {
+				return files, ioError{err}
+			}
+		}
+		return files, nil
+	}
+
+	if !*watch {
+		_, err := generate()
+		return err
+	}
+	watchFiles(func() []string {
+		files, err := generate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return files
+	}, nil)
+	return nil
+}
+
+// writeCalculation prints the summary of the result, the comparison of scenarios and the sensitivity if asked
+func writeCalculation(w io.Writer, project core.Project, result core.ProjectCalculationResult, sensitivity bool, sensitivityRange float64) error {
+	if err := core.WriteSummary(w, project, result); err != nil {
+		return err
+	}
+	if len(project.Scenarios) > 0 {
+		fmt.Fprintln(w)
+		if err := core.WriteScenariosComparison(w, project, project.CalculateScenarios()); err != nil {
+			return err
+		}
+	}
+	if sensitivity && len(project.Tasks) > 0 {
+		fmt.Fprintln(w)
+		analysis := core.AnalyzeSensitivity(project, core.SensitivityOptions{Range: sensitivityRange})
+		return core.WriteSensitivityTable(w, project, analysis)
+	}
+	return nil
+}
+
+func runValidate(args []string) error {
+	flags := newFlagSet("validate", "proj.txt ...", "Check the projects and print the errors with the file and line.")
+	in := addInputFlags(flags)
+	quiet := flags.Bool("q", false, "print only the errors")
+	inputs, err := parseArgs(flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var failed error
+	for _, input := range inputs {
+		_, _, err := readProject(input, in)
+		var parseError *core.ProjectParseError
+		if errors.As(err, &parseError) {
+			for _, e := range parseError.Errors() {
+				if e.Pos.File != "" {
+					fmt.Println(e.String())
+				} else if e.Pos.Line > 0 {
+					fmt.Printf("%s:%d: %s\n", input, e.Pos.Line, e.Message)
+				} else {
+					fmt.Printf("%s: %s\n", input, e.Message)
+				}
+			}
+			failed = fmt.Errorf("%d error(s) in %s", len(parseError.Errors()), input)
+		} else if err != nil {
+			return err
+		} else if !*quiet {
+			fmt.Printf("%s: ok\n", input)
+		}
+	}
+	return failed
+}
+
+func runFmt(args []string) error {
+	flags := newFlagSet("fmt", "proj.txt ...", "Lay out the project text the standard way, print it or write it back with -w.")
+	write := flags.Bool("w", false, "write the result to the file instead of stdout")
+	check := flags.Bool("check", false, "only list the files that are not formatted, fail if there are any")
+	inputs, err := parseArgs(flags, args, 1, -1)
+	if err != nil {
+		return err
+	}
+	var unformatted []string
+	for _, input := range inputs {
+		if inputFormat(input, "") != server.FormatDsl {
+			return usageErrorf("%s: only the text format can be formatted", input)
+		}
+		data, err := readInput(input)
+		if err != nil {
+			return err
+		}
+		formatted, err := core.FormatProject(string(data))
+		if err != nil {
+			return fmt.Errorf("%s:\n%w", input, err)
+		}
+		switch {
+		case *check:
+			if formatted != string(data) {
+				fmt.Println(input)
+				unformatted = append(unformatted, input)
+			}
+		case *write && input != stdio:
+			if formatted != string(data) {
+				if err := os.WriteFile(input, []byte(formatted), 0644); err != nil {
+					return ioError{err}
+				}
+			}
+		default:
+			if _, err := io.WriteString(os.Stdout, formatted); err != nil {
+				return ioError{err}
+			}
+		}
+	}
+	if len(unformatted) > 0 {
+		return fmt.Errorf("%d file(s) not formatted", len(unformatted))
+	}
+	return nil
+}
+
+func runCalc(args []string) error {
+	flags := newFlagSet("calc", "proj.txt", "Print the cost and duration of the project and its scenarios, then its assumptions, exclusions and notes.")
+	sensitivity := flags.Bool("sensitivity", false, "rank the inputs by their effect on the totals")
+	sensitivityRange := flags.Float64("range", core.DefaultSensitivityRange, "relative variation of the inputs for -sensitivity")
+	byTag := flags.Bool("by-tag", false, "group the efforts and cost of the tasks by their tags")
+	in := addInputFlags(flags)
+	positional, err := parseArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if *sensitivityRange <= 0 {
+		return usageErrorf("-range must be positive")
+	}
+	project, _, err := readProject(positional[0], in)
+	if err != nil {
+		return err
+	}
+	result := project.Calculate()
+	if err := writeCalculation(os.Stdout, project, result, *sensitivity, *sensitivityRange); err != nil {
+		return ioError{err}
+	}
+	if *byTag {
+		fmt.Println()
+		if err := core.WriteTagsReport(os.Stdout, project, result); err != nil {
+			return ioError{err}
+		}
+	}
+	if len(project.Sections()) > 0 {
+		fmt.Println()
+		if err := core.WriteSections(os.Stdout, project); err != nil {
+			return ioError{err}
+		}
+	}
+	return nil
+}
+
+func runExport(args []string) error {
+	flags := newFlagSet("export", "proj.txt", "Convert the project to YAML or JSON, scenarios are not exported.")
+	to := flags.String("to", "", "yaml or json, by the extension of -o if not set, yaml for stdout")
+	output := flags.String("o", stdio, "output file")
+	in := addInputFlags(flags)
+	positional, err := parseArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	format := *to
+	if format == "" {
+		format = outputFormat(*output, "yaml")
+	}
+	if format != "yml" && format != "yaml" && format != "json" {
+		return usageErrorf("can export to yaml or json only, not %s", format)
+	}
+	project, _, err := readProject(positional[0], in)
+	if err != nil {
+		return err
+	}
+	// the project is exported as written, not calculated
+	return writeOutput(*output, renderOutput(format, project, core.ProjectCalculationResult{}, core.ExcelOptions{}))
+}
+
+func runDiff(args []string) error {
+	flags := newFlagSet("diff", "old.txt new.txt", "Compare two versions of the estimate: the totals, roles and tasks changed.")
+	in := addInputFlags(flags)
+	positional, err := parseArgs(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	old, _, err := readProject(positional[0], in)
+	if err != nil {
+		return fmt.Errorf("%s:\n%w", positional[0], err)
+	}
+	new, _, err := readProject(positional[1], in)
+	if err != nil {
+		return fmt.Errorf("%s:\n%w", positional[1], err)
+	}
+	if err := core.WriteDiff(os.Stdout, new, core.DiffProjects(old, new)); err != nil {
+		return ioError{err}
+	}
+	return nil
+}
+
+func runInit(args []string) error {
+	flags := newFlagSet("init", "[proj.txt]", "Start a new project from a template, proj.txt by default.")
+	templateName := flags.String("template", "web", "template: "+templateNames())
+	opts := core.TemplateOptions{}
+	flags.StringVar(&opts.Name, "name", "", "project name")
+	flags.StringVar(&opts.Author, "author", "", "author")
+	currency := flags.String("currency", "usd", "usd or eur")
+	timeUnit := flags.String("time-unit", "day", "hr, day, week or mth")
+	interactive := flags.Bool("i", false, "ask for the template, project name, author, currency and time unit")
+	list := flags.Bool("list", false, "list the templates")
+	force := flags.Bool("force", false, "overwrite the existing file")
+	positional, err := parseArgs(flags, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if *list {
+		for _, t := range core.ProjectTemplates {
+			fmt.Printf("%-8s %s\n", t.Name, t.Description)
+		}
+		return nil
+	}
+	path := "proj.txt"
+	if len(positional) > 0 {
+		path = positional[0]
+	}
+	if _, err := os.Stat(path); err == nil && !*force && path != stdio {
+		return usageErrorf("%s exists, use -force to overwrite it", path)
+	}
+	if *interactive {
+		// the prompts must not get into the project written to stdout
+		out := os.Stdout
+		if path == stdio {
+			out = os.Stderr
+		}
+		p := prompter{in: bufio.NewReader(os.Stdin), out: out}
+		*templateName = p.ask("Template ("+templateNames()+")", *templateName, func(v string) bool {
+			_, found := core.TemplateByName(v)
+			return found
+		})
+		opts.Name = p.ask("Project name", opts.Name, nil)
+		opts.Author = p.ask("Author", opts.Author, nil)
+		*currency = p.ask("Currency (usd, eur)", *currency, func(v string) bool {
+			return core.CurrencyFromString(v) != core.CurrencyUnknown
+		})
+		*timeUnit = p.ask("Time unit (hr, day, week, mth)", *timeUnit, func(v string) bool {
+			return core.TimeUnitFromString(v) != core.TimeUnitUnknown
+		})
+		if p.err != nil {
+			return ioError{p.err}
+		}
+	}
+
+	template, found := core.TemplateByName(*templateName)
+	if !found {
+		return usageErrorf("unknown template: %s, use one of %s", *templateName, templateNames())
+	}
+	if opts.Currency = core.CurrencyFromString(*currency); opts.Currency == core.CurrencyUnknown {
+		return usageErrorf("unknown currency: %s", *currency)
+	}
+	if opts.TimeUnit = core.TimeUnitFromString(*timeUnit); opts.TimeUnit == core.TimeUnitUnknown {
+		return usageErrorf("unknown time unit: %s", *timeUnit)
+	}
+	if err := writeOutput(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, template.Render(opts))
+		return err
+	}); err != nil {
+		return err
+	}
+	if path != stdio {
+		fmt.Printf("Created %s, now run: estimatorium generate %s report.xlsx\n", path, path)
+	}
+	return nil
+}
+
+func templateNames() string {
+	var names []string
+	for _, t := range core.ProjectTemplates {
+		names = append(names, t.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// prompter asks the questions of init -i, the first error stops asking
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	err error
+}
+
+// ask returns the answer, or the default for the empty one. It asks again until valid accepts the answer.
+func (p *prompter) ask(question, defaultVal string, valid func(string) bool) string {
+	for p.err == nil {
+		if defaultVal != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, defaultVal)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		line, err := p.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				// the input is over, take the defaults for the rest
+				fmt.Fprintln(p.out)
+				return defaultVal
+			}
+			p.err = err
+			break
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = defaultVal
+		}
+		if valid == nil || valid(answer) {
+			return answer
+		}
+		fmt.Fprintf(p.out, "Wrong value: %s\n", answer)
+	}
+	return defaultVal
+}
+
+func runServe(args []string) error {
+	flags := newFlagSet("serve", "", "Run the editor and the JSON API.")
+	addr := flags.String("addr", ":8080", "address to listen on")
+	if _, err := parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	fmt.Printf("Editor and API at http://%s\n", listenURLHost(*addr))
+	if err := server.ListenAndServe(*addr); err != nil {
+		return ioError{err}
+	}
+	return nil
+}
+
+// listenURLHost gives the host to open in the browser, localhost when listening on all interfaces like ":8080"
+func listenURLHost(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+func runLsp(args []string) error {
+	flags := newFlagSet("lsp", "", "Run the language server over stdio for editors.")
+	if _, err := parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	// stdout is the protocol channel, so nothing else may be printed there
+	if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+		return ioError{err}
+	}
+	return nil
+}
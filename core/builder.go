@@ -0,0 +1,92 @@
+package core
+
+// ProjectBuilder constructs the project from Go code:
+//
+//	project, err := NewProject().
+//		Name("Shop").
+//		Team(Resource{Id: "be", Count: 2, Rate: 40}, Resource{Id: "qa", Count: 1, Rate: 20, Formula: "be*0.3"}).
+//		Risk("high", 2.5).
+//		Task(Task{Category: "API", Title: "Login", Risk: "high", Work: map[string]float64{"be": 3}}).
+//		Build()
+//
+// Build checks the project the same way as ProjectFromString does.
+type ProjectBuilder struct {
+	project Project
+}
+
+// NewProject starts the project estimated in days and USD with StandardRisks
+func NewProject() *ProjectBuilder {
+	return &ProjectBuilder{project: Project{TimeUnit: Day, Currency: Usd, Risks: StandardRisks()}}
+}
+
+func (b *ProjectBuilder) Name(name string) *ProjectBuilder {
+	b.project.Name = name
+	return b
+}
+func (b *ProjectBuilder) Author(author string) *ProjectBuilder {
+	b.project.Author = author
+	return b
+}
+func (b *ProjectBuilder) TimeUnit(timeUnit TimeUnit) *ProjectBuilder {
+	b.project.TimeUnit = timeUnit
+	return b
+}
+func (b *ProjectBuilder) Currency(currency Currency) *ProjectBuilder {
+	b.project.Currency = currency
+	return b
+}
+func (b *ProjectBuilder) AcceptancePercent(percent float64) *ProjectBuilder {
+	b.project.AcceptancePercent = percent
+	return b
+}
+func (b *ProjectBuilder) DesiredDuration(duration Duration) *ProjectBuilder {
+	b.project.DesiredDuration = duration
+	return b
+}
+
+// Risk adds the risk or overrides the factor of the existing one
+func (b *ProjectBuilder) Risk(name string, factor float64) *ProjectBuilder {
+	b.project.Risks[name] = factor
+	return b
+}
+
+// Risks replaces all the risks, StandardRisks included
+func (b *ProjectBuilder) Risks(risks map[string]float64) *ProjectBuilder {
+	b.project.Risks = map[string]float64{}
+	for name, factor := range risks {
+		b.project.Risks[name] = factor
+	}
+	return b
+}
+
+// Team adds the team members, the standard resource types get their titles if not set
+func (b *ProjectBuilder) Team(resources ...Resource) *ProjectBuilder {
+	for _, r := range resources {
+		if r.Title == "" {
+			r.Title = defaultTitle(r.Id)
+		}
+		b.project.Team = append(b.project.Team, r)
+	}
+	return b
+}
+
+func (b *ProjectBuilder) Task(tasks ...Task) *ProjectBuilder {
+	for _, t := range tasks {
+		work := map[string]float64{}
+		for resId, effort := range t.Work {
+			work[resId] = effort
+		}
+		t.Work = work
+		b.project.Tasks = append(b.project.Tasks, t)
+	}
+	return b
+}
+
+// Build returns the project along with *ProjectParseError listing all the problems found, if any
+func (b *ProjectBuilder) Build() (Project, error) {
+	proj := b.project.clone()
+	if err := proj.Validate(); err != nil {
+		return proj, err
+	}
+	return proj, nil
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	project, err := NewProject().
+		Name("Project Name").
+		AcceptancePercent(10).
+		Risks(map[string]float64{"low": 1.1, "medium": 1.5, "high": 2}).
+		Team(
+			Resource{Id: "b", Count: 1, Rate: 80, Title: "Blockchain"},
+			Resource{Id: "be", Count: 2, Rate: 40},
+			Resource{Id: "fe", Count: 1, Rate: 30},
+			Resource{Id: "qa", Count: 1, Rate: 20, Formula: "(be+fe)*0.3"},
+			Resource{Id: "pm", Count: 1, Rate: 50, Formula: "fe*0.33"},
+		).
+		Task(
+			Task{Category: "Initial", Title: "Research", Risk: "low", Work: map[string]float64{"be": 3, "fe": 3}},
+			Task{Category: "Initial", Title: "Bootstrap", Risk: "medium", Work: map[string]float64{"be": 1, "fe": 10}},
+			Task{Category: "API", Title: "API task 1", Risk: "high", Work: map[string]float64{"be": 20}},
+			Task{Category: "API", Title: "API task 2", Work: map[string]float64{"be": 2}},
+		).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.ResourceById("be").Title != "Back dev" {
+		t.Fatalf("must have standard title")
+	}
+	parsed := mustNoError(t, projData)
+	assertFloat(t, "cost", parsed.Calculate().Cost, project.Calculate().Cost)
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := NewProject().
+		AcceptancePercent(150).
+		Risk("low", 0.5).
+		Team(Resource{Id: "be", Count: -1, Rate: -40}).
+		Task(Task{Category: "API", Title: "Login", Risk: "unknown", Work: map[string]float64{"be": -1, "fe": 1}}).
+		Build()
+	parseError, ok := err.(*ProjectParseError)
+	if !ok {
+		t.Fatalf("must be ProjectParseError")
+	}
+	if len(parseError.errors) != 7 {
+		t.Fatalf("must report all the problems")
+	}
+}
+
+func TestBuilderDoesNotShareTasks(t *testing.T) {
+	work := map[string]float64{"be": 1}
+	project, err := NewProject().Team(Resource{Id: "be", Count: 1}).Task(Task{Category: "API", Title: "Login", Work: work}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	work["be"] = 10
+	if project.Tasks[0].Work["be"] != 1 {
+		t.Fatalf("must copy efforts")
+	}
+}
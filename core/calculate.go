@@ -3,42 +3,312 @@ package core
 import "math"
 
 type ProjectCalculationResult struct {
-	Team []Resource // team calculated based on desired duration
+	Team              []Resource // team calculated based on desired duration
+	Resources         []ResourceCalculation
+	Roles             []RoleCalculation
+	Categories        []CategoryCalculation // like in the model, every category followed by its nested ones
+	Efforts           float64               // in project time units
+	EffortsWithRisks  float64
+	Cost              float64
+	DirectCost        float64 // of the tasks at the blended rates of the roles, without the derived roles and "Cleanup & acceptance"
+	Duration          float64 // months
+	DurationWithRisks float64 // months
+}
+
+// ResourceCalculation is the share of work of a team member, "Cleanup & acceptance" included
+type ResourceCalculation struct {
+	Resource
+	Efforts          float64 // in project time units
+	EffortsWithRisks float64
+	Cost             float64
+}
+
+// RoleCalculation sums up all seniority levels of a role efforts in tasks are stated against
+type RoleCalculation struct {
+	Role              Resource
+	Efforts           float64 // as stated in tasks, in project time units
+	EffortsWithRisks  float64
+	Cost              float64
+	BlendedRate       float64 // cost of an hour of efforts as stated in tasks
+	Duration          float64 // months
+	DurationWithRisks float64 // months
+}
+
+// CategoryCalculation sums up the tasks of a category and its nested categories, the efforts are without "Cleanup & acceptance"
+type CategoryCalculation struct {
+	Category         string             // the levels joined with "/", like "API/Auth"
+	Depth            int                // 1 for the top level categories, only they sum up to the project totals
+	Efforts          map[string]float64 // per work role, in project time units
+	EffortsWithRisks map[string]float64
+	DirectCost       float64 // at the blended rates of the roles
+	Cost             float64 // the direct cost with its share of the derived roles and "Cleanup & acceptance"
+}
+
+func (cc CategoryCalculation) TotalEfforts() float64 {
+	return sumValues(cc.Efforts)
+}
+func (cc CategoryCalculation) TotalEffortsWithRisks() float64 {
+	return sumValues(cc.EffortsWithRisks)
+}
+
+// TaskCalculation is the share of a task in the project totals, the efforts are without "Cleanup & acceptance"
+type TaskCalculation struct {
+	Task
+	Efforts          float64 // in project time units
+	EffortsWithRisks float64
+	DirectCost       float64 // at the blended rates of the roles, like in CategoryCalculation
+	Cost             float64
+}
+
+// CalculateTask gives the efforts and cost of the task of the project calculated into result,
+// for the optional task it's what including it would add
+func (p Project) CalculateTask(task Task, result ProjectCalculationResult) TaskCalculation {
+	res := TaskCalculation{Task: task}
+	hrs := float64(p.TimeUnit.ToHours())
+	for resId, effort := range task.Work {
+		withRisk := effortWithRisk(effort, p.RiskFactor(task.Risk))
+		res.Efforts += effort
+		res.EffortsWithRisks += withRisk
+		for _, role := range result.Roles {
+			if role.Role.Id == resId {
+				res.DirectCost += withRisk * hrs * role.BlendedRate
+			}
+		}
+	}
+	res.Cost = res.DirectCost * result.indirectFactor()
+	return res
+}
+
+// indirectFactor spreads the cost of the derived roles and "Cleanup & acceptance" over the tasks
+// in proportion to their direct cost
+func (result ProjectCalculationResult) indirectFactor() float64 {
+	if result.DirectCost == 0 {
+		return 0
+	}
+	return result.Cost / result.DirectCost
+}
+
+func sumValues(m map[string]float64) float64 {
+	res := 0.0
+	for _, v := range m {
+		res += v
+	}
+	return res
 }
 
 func (p *Project) Calculate() ProjectCalculationResult {
 	res := ProjectCalculationResult{}
 
-	if p.DesiredDuration == (Duration{}) {
-		return res
+	work, workWithRisks := p.workOfRoles()
+
+	if p.DesiredDuration != (Duration{}) {
+		p.Team = p.teamForDuration(workWithRisks)
+	}
+	res.Team = p.Team
+
+	acceptance := 1 + p.AcceptancePercent/100
+	hrs := float64(p.TimeUnit.ToHours())
+
+	for _, resource := range p.Team {
+		rc := ResourceCalculation{Resource: resource}
+		if resource.Formula != "" {
+			// formula is validated by parser
+			rc.Efforts, _ = evalFormula(resource.Formula, work)
+			rc.EffortsWithRisks, _ = evalFormula(resource.Formula, workWithRisks)
+		} else {
+			share := p.shareOfRole(resource)
+			rc.Efforts = work[resource.Role()] * share
+			rc.EffortsWithRisks = workWithRisks[resource.Role()] * share
+		}
+		rc.Efforts *= acceptance
+		rc.EffortsWithRisks *= acceptance
+		rc.Cost = hrs * rc.EffortsWithRisks * resource.Rate
+		res.Resources = append(res.Resources, rc)
+		res.Efforts += rc.Efforts
+		res.EffortsWithRisks += rc.EffortsWithRisks
+		res.Cost += rc.Cost
 	}
 
-	desiredDuration := p.DesiredDuration
-	desiredDurationHrs := desiredDuration.ToHours()
+	for _, role := range p.WorkRoles() {
+		rc := RoleCalculation{
+			Role:             role,
+			Efforts:          work[role.Id] * acceptance,
+			EffortsWithRisks: workWithRisks[role.Id] * acceptance,
+		}
+		for _, resource := range res.Resources {
+			if resource.Formula != "" || resource.Role() != role.Id {
+				continue
+			}
+			rc.Cost += resource.Cost
+			rc.Duration = math.Max(rc.Duration, p.toMonths(durationOf(resource.Efforts, resource.Count)))
+			rc.DurationWithRisks = math.Max(rc.DurationWithRisks, p.toMonths(durationOf(resource.EffortsWithRisks, resource.Count)))
+		}
+		if rc.EffortsWithRisks > 0 {
+			rc.BlendedRate = rc.Cost / (rc.EffortsWithRisks * hrs)
+		}
+		res.DirectCost += workWithRisks[role.Id] * hrs * rc.BlendedRate
+		res.Roles = append(res.Roles, rc)
+		res.Duration = math.Max(res.Duration, rc.Duration)
+		res.DurationWithRisks = math.Max(res.DurationWithRisks, rc.DurationWithRisks)
+	}
+
+	res.Categories = p.calculateCategories(res)
+
+	return res
+}
+
+func (p Project) calculateCategories(result ProjectCalculationResult) []CategoryCalculation {
+	var res []CategoryCalculation
+	// the categories of the optional tasks are there too, as in the model
+	var add func(node *categoryNode) CategoryCalculation
+	add = func(node *categoryNode) CategoryCalculation {
+		i := len(res)
+		res = append(res, CategoryCalculation{
+			Category:         node.path,
+			Depth:            node.depth,
+			Efforts:          map[string]float64{},
+			EffortsWithRisks: map[string]float64{},
+		})
+		for _, task := range node.tasks {
+			if task.Optional {
+				continue
+			}
+			for resId, effort := range task.Work {
+				res[i].Efforts[resId] += effort
+				res[i].EffortsWithRisks[resId] += effortWithRisk(effort, p.RiskFactor(task.Risk))
+			}
+		}
+		for _, child := range node.children {
+			nested := add(child)
+			for resId, effort := range nested.Efforts {
+				res[i].Efforts[resId] += effort
+			}
+			for resId, effort := range nested.EffortsWithRisks {
+				res[i].EffortsWithRisks[resId] += effort
+			}
+		}
+		return res[i]
+	}
+	for _, node := range categoryTree(p.Tasks) {
+		add(node)
+	}
+	hrs := float64(p.TimeUnit.ToHours())
+	for i := range res {
+		for _, role := range result.Roles {
+			res[i].DirectCost += res[i].EffortsWithRisks[role.Role.Id] * hrs * role.BlendedRate
+		}
+		res[i].Cost = res[i].DirectCost * result.indirectFactor()
+	}
+	return res
+}
 
-	work := map[string]float64{}
+// workOfRoles sums up efforts of tasks per role in project time units, the optional tasks are left out
+func (p Project) workOfRoles() (work map[string]float64, workWithRisks map[string]float64) {
+	work = map[string]float64{}
+	workWithRisks = map[string]float64{}
+	for _, role := range p.WorkRoles() {
+		work[role.Id] = 0
+		workWithRisks[role.Id] = 0
+	}
 	for _, task := range p.Tasks {
+		if task.Optional {
+			continue
+		}
 		for resId, effort := range task.Work {
-			work[resId] += effort * float64(p.TimeUnit.ToHours()) * p.Risks[task.Risk]
+			work[resId] += effort
+			workWithRisks[resId] += effortWithRisk(effort, p.RiskFactor(task.Risk))
 		}
 	}
+	return
+}
+
+// effortWithRisk mirrors ROUNDUP of the generated Excel model
+func effortWithRisk(effort, riskFactor float64) float64 {
+	return math.Ceil(effort*riskFactor - 1e-9)
+}
+
+// shareOfRole is the part of efforts stated in tasks for the role a team member has to put in.
+// Seniority levels split the work proportionally to count*velocity so that all of them finish at the same time.
+func (p Project) shareOfRole(resource Resource) float64 {
+	if resource.Level() == "" {
+		return 1 / resource.velocity()
+	}
+	capacity := 0.0
+	for _, r := range p.ResourcesOfRole(resource.Role()) {
+		capacity += float64(r.Count) * r.velocity()
+	}
+	if capacity == 0 {
+		return 0
+	}
+	return float64(resource.Count) / capacity
+}
+
+func durationOf(efforts float64, count int) float64 {
+	if efforts == 0 {
+		return 0
+	}
+	if count == 0 {
+		return math.Inf(1)
+	}
+	return efforts / float64(count)
+}
+
+// toMonths converts project time units to months
+func (p Project) toMonths(v float64) float64 {
+	return v * float64(p.TimeUnit.ToHours()) / WorkingHoursADay / WorkingDaysInMonth
+}
+
+func (p Project) teamForDuration(workWithRisks map[string]float64) []Resource {
+	desiredDurationHrs := p.DesiredDuration.ToHours()
+	hrs := float64(p.TimeUnit.ToHours())
 
 	calculatedTeam := []Resource{}
 	for _, resource := range p.Team {
-		workOfRes := work[resource.Id]
-		if workOfRes > 0 {
-			cntF := workOfRes / desiredDurationHrs
-			cnt := int(math.Ceil(cntF))
+		workOfRes := workWithRisks[resource.Role()] * hrs
+		if resource.Formula != "" {
 			resource1 := resource
-			resource1.Count = cnt
+			resource1.Count = 1 // TODO
 			calculatedTeam = append(calculatedTeam, resource1)
-		} else if resource.Formula != "" {
+		} else if workOfRes > 0 && resource.Level() == "" {
+			cntF := workOfRes / resource.velocity() / desiredDurationHrs
 			resource1 := resource
-			resource1.Count = 1 // TODO
+			resource1.Count = int(math.Ceil(cntF))
+			calculatedTeam = append(calculatedTeam, resource1)
+		} else if workOfRes > 0 {
+			// keep the mix of seniority levels, just scale it to get the capacity needed
+			levels := p.ResourcesOfRole(resource.Role())
+			capacity := 0.0
+			for _, r := range levels {
+				capacity += float64(r.Count) * r.velocity()
+			}
+			cnt := float64(resource.Count)
+			if capacity == 0 {
+				for _, r := range levels {
+					capacity += r.velocity()
+				}
+				cnt = 1
+			}
+			resource1 := resource
+			resource1.Count = int(math.Ceil(cnt * workOfRes / desiredDurationHrs / capacity))
 			calculatedTeam = append(calculatedTeam, resource1)
 		}
 	}
-	p.Team = calculatedTeam
+	return calculatedTeam
+}
 
+const BaseScenarioName = "base"
+
+// ScenarioResult is the calculation of the project or one of its scenarios
+type ScenarioResult struct {
+	Name   string
+	Result ProjectCalculationResult
+}
+
+// CalculateScenarios calculates the project itself (as BaseScenarioName) followed by all its scenarios
+func (p Project) CalculateScenarios() []ScenarioResult {
+	res := []ScenarioResult{{Name: BaseScenarioName, Result: p.Calculate()}}
+	for _, scenario := range p.Scenarios {
+		res = append(res, ScenarioResult{Name: scenario.Name, Result: scenario.Project.Calculate()})
+	}
 	return res
 }
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func assertFloat(t *testing.T, name string, expected, actual float64) {
+	if math.Abs(expected-actual) > 1e-6 {
+		t.Fatalf("%s must be %v but was %v", name, expected, actual)
+	}
+}
+
+func TestCalculateCosts(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+acceptance_percent 10
+team
+be cnt=2 rate=40
+qa cnt=1 rate=20 formula=be*0.5
+tasks
+a|b|be=10 risks=low
+a|c|be=10
+`)
+	res := project.Calculate()
+	assertFloat(t, "efforts", (20+10)*1.1, res.Efforts)
+	assertFloat(t, "efforts with risks", (21+10.5)*1.1, res.EffortsWithRisks)
+	assertFloat(t, "cost", 8*21*1.1*40+8*10.5*1.1*20, res.Cost)
+	assertFloat(t, "duration", 21*1.1/2/WorkingDaysInMonth, res.DurationWithRisks)
+}
+
+func TestCalculateSeniorityLevels(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+team
+be.senior cnt=1 rate=60 velocity=2
+be.junior cnt=2 rate=30 velocity=0.5
+tasks
+a|b|be=30
+`)
+	res := project.Calculate()
+	// capacity is 1*2+2*0.5 = 3 standard developers, so 10 days
+	assertFloat(t, "senior efforts", 10, res.Resources[0].Efforts)
+	assertFloat(t, "junior efforts", 20, res.Resources[1].Efforts)
+	assertFloat(t, "cost", 8*(10*60+20*30), res.Cost)
+	assertFloat(t, "blended rate", (10*60+20*30)/30.0, res.Roles[0].BlendedRate)
+	assertFloat(t, "duration", 10.0/WorkingDaysInMonth, res.Duration)
+}
+
+func TestDesiredDurationWithSeniorityLevels(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+desired_duration 1mth
+team
+be.senior cnt=1 velocity=2
+be.junior cnt=1
+tasks
+a|b|be=120
+`)
+	res := project.Calculate()
+	team := map[string]int{}
+	for _, r := range res.Team {
+		team[r.Id] = r.Count
+	}
+	// 1 senior and 1 junior make 3 days of work a day, the mix 1:1 is scaled to make it in a month
+	daysPerMonth := float64(Month.ToHours()) / float64(Day.ToHours())
+	expected := int(math.Ceil(120 / daysPerMonth / 3))
+	if expected < 2 || team["be.senior"] != expected || team["be.junior"] != expected {
+		t.Fatalf("must be %d seniors and %d juniors: %v", expected, expected, team)
+	}
+	if res.DurationWithRisks > 1 {
+		t.Fatalf("must fit the desired duration: %v", res.DurationWithRisks)
+	}
+}
+
+func TestSensitivity(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+team
+be cnt=1 rate=40
+fe cnt=1 rate=10
+tasks
+a|b|be=10 fe=10
+a|c|be=1
+`)
+	analysis := AnalyzeSensitivity(project, SensitivityOptions{Range: 0.5})
+	top := analysis.Items[0]
+	if top.Input != "Efforts of a | b" {
+		t.Fatalf("efforts of the biggest task must move the cost most, but was %s", top.Input)
+	}
+	assertFloat(t, "cost low", 8*(5*40+5*10+1*40), top.CostLow)
+	assertFloat(t, "cost high", 8*(15*40+15*10+1*40), top.CostHigh)
+	assertFloat(t, "base cost", 8*(11*40+10*10), analysis.Base.Cost)
+	if project.Tasks[0].Work["be"] != 10 {
+		t.Fatalf("project must stay intact")
+	}
+}
+
+func TestSensitivityWithinDomain(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+acceptance_percent 90
+risks low=1.1
+team
+be cnt=1 rate=40
+tasks
+a|b|be=10 risks=low
+`)
+	for _, item := range AnalyzeSensitivity(project, SensitivityOptions{Range: 0.5}).Items {
+		switch item.Input {
+		case "Risk low":
+			assertFloat(t, "risk low", 1, item.Low)
+			assertFloat(t, "risk high", 1.65, item.High)
+		case "Cleanup & acceptance":
+			assertFloat(t, "acceptance low", 45, item.Low)
+			assertFloat(t, "acceptance high", 100, item.High)
+		}
+	}
+}
+
+func TestSensitivitySkipsNoise(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+team
+be cnt=1 rate=40
+ds cnt=1 rate=0
+tasks
+a|b|be=10
+a|c|be=5 optional risks=high
+a|d|be=0
+`)
+	var inputs []string
+	for _, item := range AnalyzeSensitivity(project, SensitivityOptions{}).Items {
+		inputs = append(inputs, item.Input)
+	}
+	if strings.Join(inputs, ", ") != "Efforts of a | b, Rate of Back dev" {
+		t.Fatalf("the inputs at 0, the optional tasks and the unused risks must be skipped: %v", inputs)
+	}
+}
+
+func TestCalculateCategories(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+acceptance_percent 10
+team
+be cnt=1 rate=40
+fe cnt=1 rate=10
+tasks
+API|b|be=10 fe=2 risks=low
+UI|c|fe=5
+API|d|be=1
+`)
+	res := project.Calculate()
+	if len(res.Categories) != 2 || res.Categories[0].Category != "API" {
+		t.Fatalf("must be 2 categories in order of appearance")
+	}
+	api := res.Categories[0]
+	assertFloat(t, "API be efforts", 11, api.Efforts["be"])
+	assertFloat(t, "API efforts with risks", 11+1+3, api.TotalEffortsWithRisks())
+	assertFloat(t, "API direct cost", 8*(12*40+3*10), api.DirectCost)
+	assertFloat(t, "API cost", 8*(12*40+3*10)*1.1, api.Cost)
+}
+
+func TestCategoriesSumUpToCost(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+acceptance_percent 10
+team
+be cnt=1 rate=40
+fe cnt=1 rate=10
+qa cnt=1 rate=20 formula=be*0.3
+pm cnt=1 rate=50 formula=(be+fe)*0.1
+tasks
+API|b|be=10 fe=2 risks=low
+UI|c|fe=5
+API|d|be=1
+UI|e|fe=3 optional
+`)
+	res := project.Calculate()
+	categories, categoriesDirect, tasks := 0.0, 0.0, 0.0
+	for _, cc := range res.Categories {
+		categories += cc.Cost
+		categoriesDirect += cc.DirectCost
+	}
+	for _, task := range project.Tasks {
+		if !task.Optional {
+			tasks += project.CalculateTask(task, res).Cost
+		}
+	}
+	assertFloat(t, "categories cost", res.Cost, categories)
+	assertFloat(t, "categories direct cost", res.DirectCost, categoriesDirect)
+	assertFloat(t, "tasks cost", res.Cost, tasks)
+	if res.DirectCost >= res.Cost {
+		t.Fatalf("the direct cost must be without the derived roles and acceptance: %v %v", res.DirectCost, res.Cost)
+	}
+}
+
+func TestCalculateTask(t *testing.T) {
+	project := mustNoError(t, `
+time_unit day
+team
+be cnt=1 rate=40
+fe cnt=1 rate=30
+tasks
+a|b|be=10 fe=2 risks=medium
+a|c|be=4
+`)
+	res := project.Calculate()
+	task := project.CalculateTask(project.Tasks[0], res)
+	assertFloat(t, "efforts", 12, task.Efforts)
+	assertFloat(t, "efforts with risks", 15+3, task.EffortsWithRisks)
+	assertFloat(t, "cost", 8*(15*40+3*30), task.Cost)
+	other := project.CalculateTask(project.Tasks[1], res)
+	assertFloat(t, "cost of all tasks", res.Categories[0].Cost, task.Cost+other.Cost)
+}
@@ -0,0 +1,64 @@
+package core
+
+import "strings"
+
+// categorySeparator separates the levels of the nested categories like "API/Auth",
+// the include prefix is joined with the category of the included task the same way
+const categorySeparator = "/"
+
+// categoryNode is the category along with its tasks and the nested categories
+type categoryNode struct {
+	path     string // the levels joined with categorySeparator, without the spaces around them
+	depth    int    // 1 for the top level
+	tasks    []Task // of the category itself, not the nested ones
+	children []*categoryNode
+}
+
+// categoryPath splits the category into its levels, "API / Auth" is "API/Auth"
+func categoryPath(category string) []string {
+	var res []string
+	for _, level := range strings.Split(category, categorySeparator) {
+		res = append(res, strings.TrimSpace(level))
+	}
+	return res
+}
+
+// categoryTree groups the tasks by categories and their levels, whatever order the tasks go in.
+// The categories and the tasks keep the order of their first appearance.
+func categoryTree(tasks []Task) []*categoryNode {
+	var roots []*categoryNode
+	nodes := map[string]*categoryNode{}
+	for _, task := range tasks {
+		siblings := &roots
+		var node *categoryNode
+		path := categoryPath(task.Category)
+		for depth := 1; depth <= len(path); depth++ {
+			key := strings.Join(path[:depth], categorySeparator)
+			if nodes[key] == nil {
+				nodes[key] = &categoryNode{path: key, depth: depth}
+				*siblings = append(*siblings, nodes[key])
+			}
+			node = nodes[key]
+			siblings = &node.children
+		}
+		node.tasks = append(node.tasks, task)
+	}
+	return roots
+}
+
+// categoryMatches tells if the category is the selected one or nested in it
+func categoryMatches(selected, category string) bool {
+	if selected == category {
+		return true
+	}
+	selectedPath, path := categoryPath(selected), categoryPath(category)
+	if len(selectedPath) > len(path) {
+		return false
+	}
+	for i := range selectedPath {
+		if selectedPath[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const projNestedCategories = `
+team
+be cnt=1 rate=40
+tasks
+API/Auth    | Login  | be=2
+UI          | Theme  | be=1
+API         | Docs   | be=1
+API / Users | List   | be=3
+API/Auth    | Logout | be=1
+
+scenario web
+exclude API
+`
+
+func TestCategoryTree(t *testing.T) {
+	project := mustNoError(t, projNestedCategories)
+	var lines []string
+	var walk func(nodes []*categoryNode)
+	walk = func(nodes []*categoryNode) {
+		for _, node := range nodes {
+			var titles []string
+			for _, task := range node.tasks {
+				titles = append(titles, task.Title)
+			}
+			lines = append(lines, strings.Repeat(" ", node.depth-1)+node.path+":"+strings.Join(titles, ","))
+			walk(node.children)
+		}
+	}
+	walk(categoryTree(project.Tasks))
+	expected := "API:Docs\n API/Auth:Login,Logout\n API/Users:List\nUI:Theme"
+	if strings.Join(lines, "\n") != expected {
+		t.Fatalf("wrong tree:\n%s", strings.Join(lines, "\n"))
+	}
+	if web := project.Scenarios[0].Project; len(web.Tasks) != 1 || web.Tasks[0].Title != "Theme" {
+		t.Fatalf("the nested categories must be excluded along: %+v", web.Tasks)
+	}
+	if categoryMatches("API", "APIs/Auth") || !categoryMatches("API/Auth", "API / Auth") {
+		t.Fatalf("wrong category matching")
+	}
+}
+
+func TestCalculateNestedCategories(t *testing.T) {
+	project := mustNoError(t, "time_unit day\n"+projNestedCategories)
+	res := project.Calculate()
+	// the same rows as the Categories table of the model
+	var lines []string
+	topLevelCost := 0.0
+	for _, cc := range res.Categories {
+		lines = append(lines, fmt.Sprintf("%d %s %v", cc.Depth, cc.Category, cc.TotalEfforts()))
+		if cc.Depth == 1 {
+			topLevelCost += cc.Cost
+		}
+	}
+	if strings.Join(lines, ", ") != "1 API 7, 2 API/Auth 3, 2 API/Users 3, 1 UI 1" {
+		t.Fatalf("wrong categories: %v", lines)
+	}
+	assertFloat(t, "top level categories cost", res.Cost, topLevelCost)
+	assertFloat(t, "API cost", res.Categories[1].Cost+res.Categories[2].Cost+8*40, res.Categories[0].Cost)
+}
+
+func TestYamlNestedCategories(t *testing.T) {
+	project, err := ProjectFromYaml(`
+team:
+  be: {cnt: 1, rate: 40}
+tasks:
+  - cat: API
+    tasks:
+      - title: Docs
+        be: 1
+      - cat: Auth
+        tasks:
+          - title: Login
+            be: 2
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Tasks) != 2 || project.Tasks[0].Category != "API" || project.Tasks[1].Category != "API/Auth" {
+		t.Fatalf("wrong categories: %+v", project.Tasks)
+	}
+}
+
+func TestExcelNestedCategories(t *testing.T) {
+	project := mustNoError(t, projNestedCategories)
+	f := generateAndOpen(t, project, ExcelOptions{})
+	rows, err := f.GetRows(DefaultSheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header rows, then API: Docs, API/Auth: Login, Logout, subtotal, API/Users: List, subtotal, API subtotal, UI: Theme, subtotal
+	expected := []struct {
+		feature, story string
+		level          uint8
+	}{
+		{"API", "Docs", 1},
+		{"API/Auth", "Login", 2},
+		{"API/Auth", "Logout", 2}, // merged
+		{"", "Subtotal", 1},
+		{"API/Users", "List", 2},
+		{"", "Subtotal", 1},
+		{"API", "Subtotal", 0},
+		{"UI", "Theme", 1},
+		{"", "Subtotal", 0},
+	}
+	for i, e := range expected {
+		rowZ := i + 2
+		level, err := f.GetRowOutlineLevel(DefaultSheetName, rowZ+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[rowZ][0] != e.feature || rows[rowZ][1] != e.story || level != e.level {
+			t.Fatalf("wrong row %d: %v level %d", rowZ+1, rows[rowZ], level)
+		}
+	}
+	// the API subtotal covers the nested categories, their subtotal rows are skipped having no Include
+	formula, err := f.GetCellFormula(DefaultSheetName, "D9")
+	if err != nil || formula != `SUMIF(F3:F8,"Yes",D3:D8)` {
+		t.Fatalf("wrong API subtotal: %s %v", formula, err)
+	}
+	if value, err := f.CalcCellValue(DefaultSheetName, "D9"); err != nil || value != "7" {
+		t.Fatalf("wrong API subtotal value: %s %v", value, err)
+	}
+}
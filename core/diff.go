@@ -0,0 +1,151 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// ProjectDiff compares two versions of the estimate
+type ProjectDiff struct {
+	Old, New ProjectCalculationResult
+	Roles    []RoleDiff
+	Tasks    []TaskDiff // only the added, removed and changed ones
+}
+
+// RoleDiff is the change of efforts of a work role, with risks
+type RoleDiff struct {
+	Role       string
+	Title      string
+	OldEfforts float64
+	NewEfforts float64
+}
+
+type TaskChange int8
+
+const (
+	TaskAdded TaskChange = iota
+	TaskRemoved
+	TaskChanged
+)
+
+// TaskDiff is the change of a task found by its category and title
+type TaskDiff struct {
+	Category   string
+	Title      string
+	Change     TaskChange
+	OldEfforts float64 // with risks, 0 for added
+	NewEfforts float64 // with risks, 0 for removed
+	OldCost    float64
+	NewCost    float64
+}
+
+type taskKey struct {
+	category string
+	title    string
+}
+
+// DiffProjects calculates both versions and finds what has changed
+func DiffProjects(old, new Project) ProjectDiff {
+	res := ProjectDiff{Old: old.Calculate(), New: new.Calculate()}
+
+	roleIdx := map[string]int{}
+	for _, r := range res.Old.Roles {
+		roleIdx[r.Role.Id] = len(res.Roles)
+		res.Roles = append(res.Roles, RoleDiff{Role: r.Role.Id, Title: r.Role.Title, OldEfforts: r.EffortsWithRisks})
+	}
+	for _, r := range res.New.Roles {
+		i, exists := roleIdx[r.Role.Id]
+		if !exists {
+			i = len(res.Roles)
+			res.Roles = append(res.Roles, RoleDiff{Role: r.Role.Id, Title: r.Role.Title})
+		}
+		res.Roles[i].NewEfforts = r.EffortsWithRisks
+	}
+
+	newTasks := map[taskKey]TaskCalculation{}
+	for _, task := range new.Tasks {
+		newTasks[taskKey{task.Category, task.Title}] = new.CalculateTask(task, res.New)
+	}
+	seen := map[taskKey]bool{}
+	for _, task := range old.Tasks {
+		key := taskKey{task.Category, task.Title}
+		seen[key] = true
+		oldCalc := old.CalculateTask(task, res.Old)
+		diff := TaskDiff{Category: task.Category, Title: task.Title, OldEfforts: oldCalc.EffortsWithRisks, OldCost: oldCalc.Cost}
+		newCalc, exists := newTasks[key]
+		if !exists {
+			diff.Change = TaskRemoved
+			res.Tasks = append(res.Tasks, diff)
+			continue
+		}
+		diff.Change = TaskChanged
+		diff.NewEfforts = newCalc.EffortsWithRisks
+		diff.NewCost = newCalc.Cost
+		if !sameWork(task, newCalc.Task) || task.Risk != newCalc.Risk || diff.OldCost != diff.NewCost {
+			res.Tasks = append(res.Tasks, diff)
+		}
+	}
+	for _, task := range new.Tasks {
+		key := taskKey{task.Category, task.Title}
+		if !seen[key] {
+			newCalc := newTasks[key]
+			res.Tasks = append(res.Tasks, TaskDiff{Category: task.Category, Title: task.Title, Change: TaskAdded,
+				NewEfforts: newCalc.EffortsWithRisks, NewCost: newCalc.Cost})
+		}
+	}
+	return res
+}
+
+func sameWork(a, b Task) bool {
+	if len(a.Work) != len(b.Work) {
+		return false
+	}
+	for resId, effort := range a.Work {
+		if other, exists := b.Work[resId]; !exists || other != effort {
+			return false
+		}
+	}
+	return true
+}
+
+var taskChangeSigns = map[TaskChange]string{TaskAdded: "+", TaskRemoved: "-", TaskChanged: "~"}
+
+// WriteDiff prints the totals of both versions side by side followed by the changed roles and tasks
+func WriteDiff(w io.Writer, project Project, diff ProjectDiff) error {
+	money := project.Currency.Format
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\tOld\tNew\tChange\t\n")
+	fmt.Fprintf(tw, "Cost\t%s\t%s\t%s\t\n", money(diff.Old.Cost), money(diff.New.Cost), moneyChange(project.Currency, diff.New.Cost-diff.Old.Cost))
+	fmt.Fprintf(tw, "Efforts with risks (%vs)\t%.1f\t%.1f\t%+.1f\t\n", project.TimeUnit,
+		diff.Old.EffortsWithRisks, diff.New.EffortsWithRisks, diff.New.EffortsWithRisks-diff.Old.EffortsWithRisks)
+	fmt.Fprintf(tw, "Duration with risks (mths)\t%.1f\t%.1f\t%+.1f\t\n",
+		diff.Old.DurationWithRisks, diff.New.DurationWithRisks, diff.New.DurationWithRisks-diff.Old.DurationWithRisks)
+	fmt.Fprintf(tw, "Team\t%d\t%d\t%+d\t\n", teamSize(diff.Old.Team), teamSize(diff.New.Team), teamSize(diff.New.Team)-teamSize(diff.Old.Team))
+	for _, r := range diff.Roles {
+		if r.OldEfforts != r.NewEfforts {
+			fmt.Fprintf(tw, "%s (%vs)\t%.1f\t%.1f\t%+.1f\t\n", r.Title, project.TimeUnit, r.OldEfforts, r.NewEfforts, r.NewEfforts-r.OldEfforts)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(diff.Tasks) == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "\nTasks:\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, t := range diff.Tasks {
+		fmt.Fprintf(tw, "%s %s | %s\t%.1f → %.1f %ss\t%s\t\n", taskChangeSigns[t.Change], t.Category, t.Title,
+			t.OldEfforts, t.NewEfforts, project.TimeUnit, moneyChange(project.Currency, t.NewCost-t.OldCost))
+	}
+	return tw.Flush()
+}
+
+// moneyChange renders the difference of amounts with the sign, like "+$1,200"
+func moneyChange(currency Currency, v float64) string {
+	if v >= 0.5 {
+		return "+" + currency.Format(v)
+	}
+	return currency.Format(v)
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffProjects(t *testing.T) {
+	old := mustNoError(t, `
+time_unit day
+team
+be cnt=1 rate=40
+tasks
+API | Login  | be=5
+API | Orders | be=10
+API | Search | be=3
+`)
+	new := mustNoError(t, `
+time_unit day
+currency usd
+team
+be cnt=1 rate=40
+fe cnt=1 rate=30
+tasks
+API | Login  | be=5
+API | Orders | be=12 risks=medium
+UI  | Form   | fe=2
+`)
+	diff := DiffProjects(old, new)
+	assertFloat(t, "old cost", 8*40*18, diff.Old.Cost)
+	if len(diff.Tasks) != 3 {
+		t.Fatalf("must be changed, removed and added tasks: %v", diff.Tasks)
+	}
+	if diff.Tasks[0].Title != "Orders" || diff.Tasks[0].Change != TaskChanged || diff.Tasks[0].NewEfforts != 18 {
+		t.Fatalf("wrong changed task: %v", diff.Tasks[0])
+	}
+	if diff.Tasks[1].Title != "Search" || diff.Tasks[1].Change != TaskRemoved {
+		t.Fatalf("wrong removed task: %v", diff.Tasks[1])
+	}
+	if diff.Tasks[2].Title != "Form" || diff.Tasks[2].Change != TaskAdded {
+		t.Fatalf("wrong added task: %v", diff.Tasks[2])
+	}
+	if len(diff.Roles) != 2 || diff.Roles[1].OldEfforts != 0 || diff.Roles[1].NewEfforts != 2 {
+		t.Fatalf("wrong roles: %v", diff.Roles)
+	}
+	var out strings.Builder
+	if err := WriteDiff(&out, new, diff); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "+ UI | Form") || !strings.Contains(out.String(), "+$2,080") {
+		t.Fatalf("wrong output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,18 @@
+// Package core turns the estimate of a software project into its cost and duration.
+//
+// The project comes either from the text description, see ProjectFromString, from YAML or JSON, see ProjectFromYaml, or from Go code, see NewProject.
+// The following is the stable API for other Go tools to compose estimates with:
+//
+//   - ProjectFromString, ProjectFromFile (with the included files), ProjectFromFileTextWith (for YAML and JSON files too),
+//     ProjectFromYaml, ProjectFromJson, NewProject and Project.Validate to get a valid Project
+//   - RateCardFromFile and Project.WithRateCard to take the rates of the team from the rate card
+//   - RolesFromFile and RegisterRoles for the organization-wide roles with their titles, rates and formulas
+//   - ProjectToYaml and ProjectToJson to write it back, without scenarios
+//   - Project.Calculate and Project.CalculateScenarios for the totals per resource, role and category
+//   - AnalyzeSensitivity to rank the inputs by their effect on the totals
+//   - GenerateExcelTo and GenerateOdsTo for the spreadsheet model with live formulas,
+//     GenerateExcel and GenerateOds to save it as a file
+//   - WriteScenariosComparison and WriteSensitivityTable for the text reports, WriteMarkdown and WriteHtml for the report to send
+//
+// Everything else, including the error messages, may change.
+package core
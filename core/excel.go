@@ -1,9 +1,12 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 	"github.com/xuri/excelize/v2"
+	"io"
 	"math"
+	"os"
 	"regexp"
 	"strings"
 	"unicode/utf8"
@@ -12,13 +15,19 @@ import (
 type excelGenerator struct {
 	colZ, rowZ           int    // current pos 0-based
 	sheet                string // current sheet
-	f                    *excelize.File
+	wb                   workbook
+	opts                 ExcelOptions
 	currencyStyleId      int
 	currencyBoldStyleId  int
 	headerStyleId        int
 	valueStyleId         int
 	valueCenteredStyleId int
 	taskNameStyleId      int
+	subtotalStyleId      int
+	percentStyleId       int
+	titleStyleId         int
+	textStyleId          int   // of the long text, wrapped
+	err                  error // the first one, the generation goes on but the result is not written
 }
 
 func (exc *excelGenerator) next() {
@@ -32,8 +41,8 @@ func (exc *excelGenerator) cr() {
 	exc.colZ = 0
 }
 func (exc *excelGenerator) setVal(val interface{}, styles ...int) {
-	checkErr(exc.f.SetCellStyle(exc.sheet, exc.currentCell(), exc.currentCell(), getCellStyle(exc, styles)))
-	checkErr(exc.f.SetCellValue(exc.sheet, exc.currentCell(), val))
+	exc.check(exc.wb.setCellStyle(exc.sheet, exc.currentCell(), getCellStyle(exc, styles)))
+	exc.check(exc.wb.setCellValue(exc.sheet, exc.currentCell(), val))
 }
 func getCellStyle(exc *excelGenerator, styles []int) int {
 	if styles == nil {
@@ -46,12 +55,22 @@ func (exc *excelGenerator) setValAndNext(val interface{}, styles ...int) {
 	exc.next()
 }
 func (exc *excelGenerator) setFormulaAndNext(formula string, styles ...int) {
-	checkErr(exc.f.SetCellStyle(exc.sheet, exc.currentCell(), exc.currentCell(), getCellStyle(exc, styles)))
-	checkErr(exc.f.SetCellFormula(exc.sheet, exc.currentCell(), formula))
-	//fmt.Println(exc.f.GetCellFormula(exc.sheet, exc.currentCell()))
+	exc.setFormulaAt(exc.currentCell(), formula, styles...)
 	exc.next()
 }
+func (exc *excelGenerator) setFormulaAt(cell string, formula string, styles ...int) {
+	exc.check(exc.wb.setCellStyle(exc.sheet, cell, getCellStyle(exc, styles)))
+	exc.check(exc.wb.setCellFormula(exc.sheet, cell, formula))
+}
+
+// check keeps the first error of the workbook, the following ones are most likely its consequences
+func (exc *excelGenerator) check(err error) {
+	if err != nil && exc.err == nil {
+		exc.err = err
+	}
+}
 
+// checkErr is for the errors that can only be caused by a bug
 func checkErr(err error) {
 	if err != nil {
 		panic(err)
@@ -63,25 +82,39 @@ func (exc *excelGenerator) mergeNext(mergeCnt int) {
 	for i := 0; i < mergeCnt; i++ {
 		exc.next()
 	}
-	checkErr(exc.f.MergeCell(exc.sheet, cell0, exc.currentCell()))
+	exc.check(exc.wb.mergeCell(exc.sheet, cell0, exc.currentCell()))
 }
 
 func (exc *excelGenerator) currentCell() string {
 	return exc.currentCellAbs(false)
 }
+func (exc *excelGenerator) cellAt(colZ, rowZ int) string {
+	return cellName(colZ, rowZ)
+}
+func cellName(colZ, rowZ int) string {
+	name, err := excelize.CoordinatesToCellName(colZ+1, rowZ+1)
+	checkErr(err)
+	return name
+}
+func absCell(cell string) string {
+	col, row, err := excelize.CellNameToCoordinates(cell)
+	checkErr(err)
+	name, err := excelize.CoordinatesToCellName(col, row, true)
+	checkErr(err)
+	return name
+}
 func (exc *excelGenerator) currentCellAbs(abs bool) string {
 	name, err := excelize.CoordinatesToCellName(exc.colZ+1, exc.rowZ+1, abs)
 	checkErr(err)
 	return name
 }
 
-func newStyle(file *excelize.File, style *excelize.Style) int {
-	styleId, err := file.NewStyle(style)
-	checkErr(err)
+func (exc *excelGenerator) newStyle(style *excelize.Style) int {
+	styleId, err := exc.wb.newStyle(style)
+	exc.check(err)
 	return styleId
 }
-func newExcelGenerator(currency Currency) *excelGenerator {
-	file := excelize.NewFile()
+func newExcelGenerator(file workbook, currency Currency, opts ExcelOptions) *excelGenerator {
 	fmtCode := "[$" + currency.Symbol() + "]#,##0"
 	borders := []excelize.Border{
 		{Type: "left", Color: "000000", Style: 1},
@@ -89,43 +122,344 @@ func newExcelGenerator(currency Currency) *excelGenerator {
 		{Type: "bottom", Color: "000000", Style: 1},
 		{Type: "right", Color: "000000", Style: 1},
 	}
-	return &excelGenerator{f: file, sheet: "Sheet1",
-		currencyStyleId:      newStyle(file, &excelize.Style{CustomNumFmt: &fmtCode, Border: borders}),
-		currencyBoldStyleId:  newStyle(file, &excelize.Style{CustomNumFmt: &fmtCode, Border: borders, Font: &excelize.Font{Bold: true}}),
-		valueStyleId:         newStyle(file, &excelize.Style{Border: borders}),
-		valueCenteredStyleId: newStyle(file, &excelize.Style{Border: borders, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}}),
-		taskNameStyleId:      newStyle(file, &excelize.Style{Border: borders, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#93c47d"}}}),
-		headerStyleId: newStyle(file, &excelize.Style{
-			Font:      &excelize.Font{Bold: true, Color: "#ffffff"},
-			Alignment: &excelize.Alignment{Horizontal: "center"},
-			Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#091e42"}},
-		})}
-}
-
-func GenerateExcel(project Project, fileName string) {
-	exc := newExcelGenerator(project.Currency)
-	taskTableInfo := generateTasksTable(exc, project)
+	styles := opts.Styles.withDefaults()
+	exc := &excelGenerator{wb: file, sheet: opts.sheetName(), opts: opts}
+	exc.currencyStyleId = exc.newStyle(&excelize.Style{CustomNumFmt: &fmtCode, Border: borders})
+	exc.currencyBoldStyleId = exc.newStyle(&excelize.Style{CustomNumFmt: &fmtCode, Border: borders, Font: &excelize.Font{Bold: true}})
+	exc.valueStyleId = exc.newStyle(&excelize.Style{Border: borders})
+	exc.valueCenteredStyleId = exc.newStyle(&excelize.Style{Border: borders, Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"}})
+	exc.taskNameStyleId = exc.newStyle(&excelize.Style{Border: borders, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{styles.TaskColor}}})
+	exc.percentStyleId = exc.newStyle(&excelize.Style{NumFmt: 10, Border: borders})
+	exc.subtotalStyleId = exc.newStyle(&excelize.Style{Border: borders, Font: &excelize.Font{Bold: true}, Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{styles.SubtotalColor}}})
+	exc.titleStyleId = exc.newStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 16}})
+	exc.textStyleId = exc.newStyle(&excelize.Style{Border: borders, Alignment: &excelize.Alignment{Vertical: "top", WrapText: true}})
+	exc.headerStyleId = exc.newStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Color: styles.HeaderFontColor},
+		Alignment: &excelize.Alignment{Horizontal: "center"},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{styles.HeaderColor}},
+	})
+	return exc
+}
+
+const DefaultSheetName = "Sheet1"
+
+type ExcelOptions struct {
+	// Compatible avoids functions missing in Excel 2013-2016, older LibreOffice and some Google Sheets imports,
+	// like SWITCH, at the cost of less readable formulas
+	Compatible bool
+	SheetName  string      // of the sheet with the model, DefaultSheetName if empty
+	Styles     ExcelStyles // colors, the defaults are used for the ones not set
+	Tables     ExcelTables // AllExcelTables if 0
+	// SensitivityRange is the relative variation of the inputs on the Sensitivity sheet, DefaultSensitivityRange if 0
+	SensitivityRange float64
+}
+
+// ExcelStyles are the colors of the workbook, like "#091e42"
+type ExcelStyles struct {
+	HeaderColor     string
+	HeaderFontColor string
+	TaskColor       string // of the task titles
+	SubtotalColor   string // of the category subtotal rows
+}
+
+var DefaultExcelStyles = ExcelStyles{
+	HeaderColor:     "#091e42",
+	HeaderFontColor: "#ffffff",
+	TaskColor:       "#93c47d",
+	SubtotalColor:   "#d9ead3",
+}
+
+func (s ExcelStyles) withDefaults() ExcelStyles {
+	if s.HeaderColor == "" {
+		s.HeaderColor = DefaultExcelStyles.HeaderColor
+	}
+	if s.HeaderFontColor == "" {
+		s.HeaderFontColor = DefaultExcelStyles.HeaderFontColor
+	}
+	if s.TaskColor == "" {
+		s.TaskColor = DefaultExcelStyles.TaskColor
+	}
+	if s.SubtotalColor == "" {
+		s.SubtotalColor = DefaultExcelStyles.SubtotalColor
+	}
+	return s
+}
+
+// ExcelTables selects the parts of the workbook besides the tasks table and the parameters, that are always there
+type ExcelTables uint
+
+const (
+	ExcelCostsTable ExcelTables = 1 << iota // along with the blended rates
+	ExcelDurationsTable
+	ExcelCategoriesTable
+	ExcelChartsSheet
+	ExcelScenariosSheet
+	ExcelSensitivitySheet
+	ExcelCoverSheet // the first sheet, if the project has assumptions, exclusions or notes
+
+	AllExcelTables = ExcelCostsTable | ExcelDurationsTable | ExcelCategoriesTable |
+		ExcelChartsSheet | ExcelScenariosSheet | ExcelSensitivitySheet | ExcelCoverSheet
+)
+
+// excelTablesNeedingCosts refer to the cells of the costs table
+const excelTablesNeedingCosts = ExcelDurationsTable | ExcelCategoriesTable | ExcelChartsSheet
+
+func (opts ExcelOptions) sheetName() string {
+	if opts.SheetName == "" {
+		return DefaultSheetName
+	}
+	return opts.SheetName
+}
+
+func (opts ExcelOptions) tables() ExcelTables {
+	if opts.Tables == 0 {
+		return AllExcelTables
+	}
+	return opts.Tables
+}
+
+func (opts ExcelOptions) has(tables ExcelTables) bool {
+	return opts.tables()&tables != 0
+}
+
+func (opts ExcelOptions) validate() error {
+	name := opts.sheetName()
+	if utf8.RuneCountInString(name) > 31 || strings.ContainsAny(name, ":\\/?*[]") {
+		return fmt.Errorf("wrong sheet name: %s", name)
+	}
+	for _, sheet := range []string{SheetParameters, SheetCharts, SheetScenarios, SheetSensitivity, SheetCover} {
+		if strings.EqualFold(name, sheet) {
+			return fmt.Errorf("sheet name is reserved: %s", name)
+		}
+	}
+	if opts.has(excelTablesNeedingCosts) && !opts.has(ExcelCostsTable) {
+		return errors.New("durations, categories and charts need the costs table")
+	}
+	return nil
+}
+
+// GenerateExcel saves the model of the project as .xlsx file
+func GenerateExcel(project Project, fileName string, opts ExcelOptions) error {
+	return generateFile(fileName, func(w io.Writer) error {
+		return GenerateExcelTo(w, project, opts)
+	})
+}
+
+// GenerateExcelTo writes the model of the project in .xlsx format
+func GenerateExcelTo(w io.Writer, project Project, opts ExcelOptions) error {
+	return generateModel(w, newXlsxWorkbook(opts.firstSheetName(project)), project, opts)
+}
+
+// GenerateOds saves the same model as GenerateExcel in OpenDocument format, without charts
+func GenerateOds(project Project, fileName string, opts ExcelOptions) error {
+	return generateFile(fileName, func(w io.Writer) error {
+		return GenerateOdsTo(w, project, opts)
+	})
+}
+
+// GenerateOdsTo writes the same model as GenerateExcelTo in OpenDocument format, without charts
+func GenerateOdsTo(w io.Writer, project Project, opts ExcelOptions) error {
+	opts.Compatible = true // SWITCH is missing in LibreOffice before 5.2
+	return generateModel(w, newOdsWorkbook(opts.firstSheetName(project)), project, opts)
+}
+
+func generateFile(fileName string, generate func(w io.Writer) error) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err = generate(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func generateModel(w io.Writer, wb workbook, project Project, opts ExcelOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	// the team counts are the inputs of the model, for desired_duration they are known after the calculation
+	project.Calculate()
+	if len(project.Team) == 0 {
+		return errors.New("no team to generate the model for")
+	}
+	if len(project.Tasks) == 0 {
+		return errors.New("no tasks to generate the model for")
+	}
+	exc := newExcelGenerator(wb, project.Currency, opts)
+	if opts.hasCoverSheet(project) {
+		// the cover is filled in the end, when the cells of the totals are known
+		exc.newSheet(opts.sheetName())
+	}
+	parametersTableInfo := generateParametersSheet(exc, project)
+	taskTableInfo := generateTasksTable(exc, project, parametersTableInfo)
 	exc.cr()
-	parametersTableInfo := parametersTableInfo{}
-	if project.AcceptancePercent > 0 {
-		parametersTableInfo = generateParametersTable(exc, project.AcceptancePercent)
+	var costsTableInfo costsTableInfo
+	if opts.has(ExcelCostsTable) {
+		costsTableInfo = generateCostsTable(exc, project, taskTableInfo)
 		exc.cr()
+		if project.UsesVelocity() {
+			generateBlendedRatesTable(exc, project, taskTableInfo, costsTableInfo)
+			exc.cr()
+		}
+		generateCategoryCosts(exc, project, taskTableInfo, costsTableInfo)
+	}
+	if opts.has(ExcelDurationsTable) {
+		generateDurationsTable(exc, project, costsTableInfo)
+		exc.cr()
+	}
+	if opts.has(ExcelCategoriesTable) {
+		generateCategoriesTable(exc, project, taskTableInfo, costsTableInfo)
 	}
-	costsTableInfo := generateCostsTable(exc, project, taskTableInfo, parametersTableInfo)
-	exc.cr()
-	generateDurationsTable(exc, project, costsTableInfo)
 
 	autoFixColWidths(exc)
 
-	checkErr(exc.f.SaveAs(fileName))
+	if opts.hasCoverSheet(project) {
+		generateCoverSheet(exc, project, costsTableInfo)
+	}
+	if opts.has(ExcelChartsSheet) {
+		generateChartsSheet(exc, project, taskTableInfo, costsTableInfo)
+	}
+	if opts.has(ExcelScenariosSheet) && len(project.Scenarios) > 0 {
+		generateScenariosSheet(exc, project, project.CalculateScenarios())
+	}
+	if opts.has(ExcelSensitivitySheet) {
+		generateSensitivitySheet(exc, project, AnalyzeSensitivity(project, SensitivityOptions{Range: opts.SensitivityRange}))
+	}
+
+	if exc.err != nil {
+		return exc.err
+	}
+	return exc.wb.write(w)
+}
+
+// setRowOutlineLevel groups the current row by the level
+func (exc *excelGenerator) setRowOutlineLevel(level int) {
+	if level > 0 {
+		exc.check(exc.wb.setRowOutlineLevel(exc.sheet, exc.rowZ+1, level))
+	}
+}
+
+// newSheet adds the sheet and makes it current for the next cells
+func (exc *excelGenerator) newSheet(name string) {
+	exc.wb.newSheet(name)
+	exc.sheet = name
+	exc.colZ = 0
+	exc.rowZ = 0
+}
+
+const SheetScenarios = "Scenarios"
+
+// generateScenariosSheet compares the scenarios side by side, the values are calculated for every scenario separately
+func generateScenariosSheet(exc *excelGenerator, project Project, results []ScenarioResult) {
+	exc.newSheet(SheetScenarios)
+	generateHeader(exc, []headerCell{
+		{title: "Scenario"},
+		{title: fmt.Sprintf("Efforts (%vs)", project.TimeUnit)},
+		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit)},
+		{title: "Team"},
+		{title: ColTotal},
+		{title: "Duration (mths)"},
+		{title: "With Risks (mths)"},
+	})
+	for _, r := range results {
+		exc.setValAndNext(r.Name, exc.headerStyleId)
+		exc.setValAndNext(math.Round(r.Result.Efforts*10) / 10)
+		exc.setValAndNext(math.Round(r.Result.EffortsWithRisks*10) / 10)
+		exc.setValAndNext(teamSize(r.Result.Team))
+		exc.setValAndNext(r.Result.Cost, exc.currencyStyleId)
+		exc.setValAndNext(math.Round(r.Result.Duration*10) / 10)
+		exc.setValAndNext(math.Round(r.Result.DurationWithRisks*10) / 10)
+		exc.cr()
+	}
+	fitColWidths(exc)
+}
+
+const (
+	SheetSensitivity        = "Sensitivity"
+	sensitivityChartMaxBars = 15
+)
+
+// generateSensitivitySheet puts the tornado table along with its chart, the values are calculated for every variation separately
+func generateSensitivitySheet(exc *excelGenerator, project Project, analysis SensitivityAnalysis) {
+	exc.newSheet(SheetSensitivity)
+	exc.setValAndNext("Base cost", exc.headerStyleId)
+	baseCostCell := exc.currentCellAbs(true)
+	exc.setValAndNext(analysis.Base.Cost, exc.currencyStyleId)
+	exc.setValAndNext("Range", exc.headerStyleId)
+	exc.setValAndNext(fmt.Sprintf("±%.0f%%", analysis.Range*100))
+	exc.cr()
+	exc.cr()
+	generateHeader(exc, []headerCell{
+		{title: "Input"},
+		{title: "Low"},
+		{title: "High"},
+		{title: "Cost Low"},
+		{title: "Cost High"},
+		{title: "Δ Cost Low"},
+		{title: "Δ Cost High"},
+		{title: "Duration Low (mths)"},
+		{title: "Duration High (mths)"},
+	})
+	firstRowZ := exc.rowZ
+	for _, item := range analysis.Items {
+		exc.setValAndNext(item.Input)
+		exc.setValAndNext(math.Round(item.Low*100) / 100)
+		exc.setValAndNext(math.Round(item.High*100) / 100)
+		costLowCell := exc.currentCell()
+		exc.setValAndNext(item.CostLow, exc.currencyStyleId)
+		costHighCell := exc.currentCell()
+		exc.setValAndNext(item.CostHigh, exc.currencyStyleId)
+		exc.setFormulaAndNext(costLowCell+"-"+baseCostCell, exc.currencyStyleId)
+		exc.setFormulaAndNext(costHighCell+"-"+baseCostCell, exc.currencyStyleId)
+		exc.setValAndNext(math.Round(item.DurationLow*10) / 10)
+		exc.setValAndNext(math.Round(item.DurationHigh*10) / 10)
+		exc.cr()
+	}
+	fitColWidths(exc)
+
+	bars := int(math.Min(float64(len(analysis.Items)), sensitivityChartMaxBars))
+	if bars == 0 {
+		return
+	}
+	lastRowZ := firstRowZ + bars - 1
+	inputs := sheetRef(exc.sheet, cellRange{exc.cellAt(0, firstRowZ), exc.cellAt(0, lastRowZ)}.String())
+	exc.addChart(exc.cellAt(10, 2), chartSpec{
+		Type:  "barStacked",
+		Title: chartTitle{Name: "Effect on cost"},
+		Series: []chartSeries{
+			{Name: sheetRef(exc.sheet, exc.cellAt(5, firstRowZ-1)), Categories: inputs,
+				Values: sheetRef(exc.sheet, cellRange{exc.cellAt(5, firstRowZ), exc.cellAt(5, lastRowZ)}.String())},
+			{Name: sheetRef(exc.sheet, exc.cellAt(6, firstRowZ-1)), Categories: inputs,
+				Values: sheetRef(exc.sheet, cellRange{exc.cellAt(6, firstRowZ), exc.cellAt(6, lastRowZ)}.String())},
+		},
+		Dimension: chartDimension{Width: 640, Height: 120 + 24*bars},
+		XAxis:     chartAxis{ReverseOrder: true}, // the most important on top
+	})
+}
+
+// fitColWidths sets widths of the current sheet columns according to their text content
+func fitColWidths(exc *excelGenerator) {
+	cols, err := exc.wb.getCols(exc.sheet)
+	exc.check(err)
+	for idx, col := range cols {
+		largestWidth := 11
+		for _, rowCell := range col {
+			largestWidth = int(math.Max(float64(largestWidth), float64(utf8.RuneCountInString(rowCell)+2)))
+		}
+		name, err := excelize.ColumnNumberToName(idx + 1)
+		checkErr(err)
+		exc.check(exc.wb.setColWidth(exc.sheet, name, float64(largestWidth)))
+	}
 }
 
 const ColTotal = "Total"
 
 func autoFixColWidths(exc *excelGenerator) {
 	// Autofit all columns according to their text content
-	cols, err := exc.f.GetCols(exc.sheet)
-	checkErr(err)
+	cols, err := exc.wb.getCols(exc.sheet)
+	exc.check(err)
 	largestWidthMap := map[int]float64{}
 	for idx, col := range cols {
 		largestWidth := 0
@@ -155,54 +489,65 @@ func autoFixColWidths(exc *excelGenerator) {
 	for idx := range cols {
 		name, err := excelize.ColumnNumberToName(idx + 1)
 		checkErr(err)
-		checkErr(exc.f.SetColWidth(exc.sheet, name, name, largestWidthMap[idx]))
+		exc.check(exc.wb.setColWidth(exc.sheet, name, largestWidthMap[idx]))
 	}
 }
 
 type tasksTableInfo struct {
-	cellRanges         map[string]*cellRange
-	cellRangesWithRisk map[string]*cellRange
+	cellRanges          map[string]*cellRange
+	cellRangesWithRisk  map[string]*cellRange
+	effortsColZ         int // first of the efforts columns, one per work role
+	effortsWithRiskColZ int
+	includeColZ         int
+	costColZ            int
+	categoryBlocks      []categoryBlock
+}
+
+// categoryBlock is the rows of the tasks of the category and its nested categories followed by their subtotal row
+type categoryBlock struct {
+	category            string
+	depth               int // 1 for the top level categories
+	firstRowZ, lastRowZ int
+	subtotalRowZ        int
+}
+
+// includedSum sums the column range of the tasks table over the tasks with Include set to Yes,
+// the subtotal rows have no Include value so they are skipped as well
+func (tti tasksTableInfo) includedSum(r cellRange) string {
+	_, firstRow, err := excelize.CellNameToCoordinates(r.hCell)
+	checkErr(err)
+	_, lastRow, err := excelize.CellNameToCoordinates(r.vCell)
+	checkErr(err)
+	includeRange := cellRange{cellName(tti.includeColZ, firstRow-1), cellName(tti.includeColZ, lastRow-1)}
+	return fmt.Sprintf("SUMIF(%s,\"%s\",%s)", includeRange, includeYes, r)
 }
 
-func generateTasksTable(exc *excelGenerator, project Project) tasksTableInfo {
+func generateTasksTable(exc *excelGenerator, project Project, parametersTableInfo parametersTableInfo) tasksTableInfo {
 	generateTasksTableHeader(exc, project)
 
 	res := tasksTableInfo{cellRanges: map[string]*cellRange{}, cellRangesWithRisk: map[string]*cellRange{}}
+	workRoles := project.WorkRoles()
+	tasksCnt := 0
 
-	riskLabels := RiskLabels(project.Risks)
-
-	startCatCell := ""
-	endCatCell := ""
-	currCat := ""
-
-	for i, t := range project.Tasks {
-		exc.setVal(t.Category, exc.valueCenteredStyleId)
-
-		if i == 0 {
-			startCatCell = exc.currentCell()
-			endCatCell = exc.currentCell()
-			currCat = t.Category
-		} else if currCat != t.Category {
-			//fmt.Printf("merging: %s, %s\n", startCatCell, endCatCell)
-			checkErr(exc.f.MergeCell(exc.sheet, startCatCell, endCatCell))
-			currCat = t.Category
-			startCatCell = exc.currentCell()
-			endCatCell = exc.currentCell()
-		} else {
-			endCatCell = exc.currentCell()
-		}
-
-		exc.next()
+	taskRow := func(t Task, level int) {
+		first, last := tasksCnt == 0, tasksCnt == len(project.Tasks)-1
+		tasksCnt++
+		exc.setRowOutlineLevel(level)
+		exc.setValAndNext(t.Category, exc.valueCenteredStyleId)
 
 		exc.setVal(t.Title, exc.taskNameStyleId)
+		if t.HasDetails() {
+			exc.check(exc.wb.addComment(exc.sheet, exc.currentCell(), t.detailsText()))
+		}
 		exc.mergeNext(1)
 		exc.next()
 		v := map[string]string{}
-		teamExcludingDerived := project.TeamExcludingDerived()
-		for _, r := range teamExcludingDerived {
-			if i == 0 {
+		res.effortsColZ = exc.colZ
+		for _, r := range workRoles {
+			if first {
 				res.cellRanges[r.Id] = &cellRange{hCell: exc.currentCell()}
-			} else if i == len(project.Tasks)-1 {
+			}
+			if last {
 				res.cellRanges[r.Id].vCell = exc.currentCell()
 			}
 			v[r.Id] = exc.currentCell()
@@ -210,139 +555,309 @@ func generateTasksTable(exc *excelGenerator, project Project) tasksTableInfo {
 		}
 		riskCell := exc.currentCell()
 
-		dv := excelize.NewDataValidation(true)
-		dv.Sqref = riskCell + ":" + riskCell
-		checkErr(dv.SetDropList(riskLabels))
-		checkErr(exc.f.AddDataValidation(exc.sheet, dv))
+		if len(parametersTableInfo.riskLabels) > 0 {
+			// RiskNames is defined for the risks there are only
+			exc.check(exc.wb.addDropList(exc.sheet, riskCell, nameRiskNames))
+		}
 
 		exc.setValAndNext(t.Risk)
-		for _, r := range teamExcludingDerived {
-			if i == 0 {
+		includeCell := exc.currentCell()
+		res.includeColZ = exc.colZ
+		exc.check(exc.wb.addDropList(exc.sheet, includeCell, nameIncludeValues))
+		if t.Optional {
+			exc.setValAndNext(includeNo)
+		} else {
+			exc.setValAndNext(includeYes)
+		}
+		res.effortsWithRiskColZ = exc.colZ
+		for _, r := range workRoles {
+			if first {
 				res.cellRangesWithRisk[r.Id] = &cellRange{hCell: exc.currentCell()}
-			} else if i == len(project.Tasks)-1 {
+			}
+			if last {
 				res.cellRangesWithRisk[r.Id].vCell = exc.currentCell()
 			}
 			//exc.setVal(t.Work[r.Id]) // TODO
-			exc.setFormulaAndNext(risksFormula(project.Risks, v[r.Id], riskCell))
-			//fmt.Println(exc.f.GetCellFormula(exc.sheet, exc.currentCell()))
+			if exc.opts.Compatible {
+				exc.setFormulaAndNext(includeFormula(includeCell, risksLookupFormula(parametersTableInfo, v[r.Id], riskCell)))
+			} else {
+				exc.setFormulaAndNext(includeFormula(includeCell, risksFormula(parametersTableInfo, v[r.Id], riskCell)))
+			}
 		}
+		res.costColZ = exc.colZ
 		exc.cr()
 	}
-	//fmt.Printf("merging: %s, %s\n", startCatCell, endCatCell)
-	checkErr(exc.f.MergeCell(exc.sheet, startCatCell, endCatCell))
+
+	// the tasks of the category go in one block followed by the blocks of the nested categories and the subtotal
+	// of them all, the rows are grouped by the levels of the categories so that they can be collapsed to the subtotals
+	var generateCategory func(node *categoryNode)
+	generateCategory = func(node *categoryNode) {
+		firstRowZ := exc.rowZ
+		for _, t := range node.tasks {
+			taskRow(t, node.depth)
+		}
+		if len(node.tasks) > 0 {
+			exc.check(exc.wb.mergeCell(exc.sheet, exc.cellAt(0, firstRowZ), exc.cellAt(0, exc.rowZ-1)))
+			exc.check(exc.wb.setCellValue(exc.sheet, exc.cellAt(0, firstRowZ), node.path))
+		}
+		// the blocks go in the order of the categories, the parent before the nested ones
+		blockIdx := len(res.categoryBlocks)
+		res.categoryBlocks = append(res.categoryBlocks, categoryBlock{})
+		for _, child := range node.children {
+			generateCategory(child)
+		}
+		block := categoryBlock{category: node.path, depth: node.depth, firstRowZ: firstRowZ, lastRowZ: exc.rowZ - 1, subtotalRowZ: exc.rowZ}
+		exc.setRowOutlineLevel(node.depth - 1)
+		generateSubtotalRow(exc, project, &res, block, len(node.children) > 0)
+		res.categoryBlocks[blockIdx] = block
+	}
+	for _, node := range categoryTree(project.Tasks) {
+		generateCategory(node)
+	}
 
 	return res
 }
 
-type parametersTableInfo struct {
-	acceptancePercentCell string
+// generateSubtotalRow sums up the included tasks of the category block, nested categories included, the sums of the whole columns skip these rows
+// having no Include value. The cost is filled by generateCategoryCosts when the rates are in place.
+func generateSubtotalRow(exc *excelGenerator, project Project, tasksTableInfo *tasksTableInfo, block categoryBlock, nested bool) {
+	if nested {
+		// the category the nested ones are summed up for
+		exc.setValAndNext(block.category, exc.subtotalStyleId)
+	} else {
+		exc.setValAndNext("", exc.subtotalStyleId)
+	}
+	exc.setVal("Subtotal", exc.subtotalStyleId)
+	exc.mergeNext(1)
+	exc.next()
+	workRoles := project.WorkRoles()
+	for i := range workRoles {
+		colZ := tasksTableInfo.effortsColZ + i
+		exc.setFormulaAndNext(tasksTableInfo.includedSum(cellRange{exc.cellAt(colZ, block.firstRowZ), exc.cellAt(colZ, block.lastRowZ)}), exc.subtotalStyleId)
+	}
+	exc.setValAndNext("", exc.subtotalStyleId)
+	exc.setValAndNext("", exc.subtotalStyleId)
+	for i := range workRoles {
+		colZ := tasksTableInfo.effortsWithRiskColZ + i
+		exc.setFormulaAndNext(tasksTableInfo.includedSum(cellRange{exc.cellAt(colZ, block.firstRowZ), exc.cellAt(colZ, block.lastRowZ)}), exc.subtotalStyleId)
+	}
+	exc.cr()
+}
+
+// generateCategoryCosts fills the cost of subtotal rows at the rates of the costs table
+func generateCategoryCosts(exc *excelGenerator, project Project, tasksTableInfo tasksTableInfo, costsTableInfo costsTableInfo) {
+	for _, block := range tasksTableInfo.categoryBlocks {
+		var parts []string
+		for i, role := range project.WorkRoles() {
+			rateCell := costsTableInfo.roleRateCells[role.Id]
+			parts = append(parts, exc.cellAt(tasksTableInfo.effortsWithRiskColZ+i, block.subtotalRowZ)+"*"+absCell(rateCell))
+		}
+		cell := exc.cellAt(tasksTableInfo.costColZ, block.subtotalRowZ)
+		exc.setFormulaAt(cell, fmt.Sprintf("%s*(%s)", nameHoursPerUnit, strings.Join(parts, "+")), exc.currencyBoldStyleId)
+	}
 }
 
-func generateParametersTable(exc *excelGenerator, acceptancePercent float64) parametersTableInfo {
-	res := parametersTableInfo{}
-	exc.setValAndNext("Cleanup & acceptance")
-	res.acceptancePercentCell = exc.currentCellAbs(true)
-	exc.setValAndNext(fmt.Sprintf("%.1f%%", acceptancePercent))
+// generateCategoriesTable lists the subtotals of the categories, the nested ones are indented under their parents.
+// The direct cost of the subtotal rows gets its share of the derived roles and "Cleanup & acceptance" in proportion to it,
+// so that the totals sum up to the cost of the project. The sum is of the top level categories only, they include the nested ones.
+func generateCategoriesTable(exc *excelGenerator, project Project, tasksTableInfo tasksTableInfo, costsTableInfo costsTableInfo) {
+	generateHeader(exc, []headerCell{
+		{title: "Category"},
+		{title: fmt.Sprintf("Efforts (%vs)", project.TimeUnit)},
+		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit)},
+		{title: "Direct cost"},
+		{title: ColTotal},
+	})
+	rolesCnt := len(project.WorkRoles())
+	firstRowZ := exc.rowZ
+	sumRowZ := firstRowZ + len(tasksTableInfo.categoryBlocks)
+	directSumCell := absCell(exc.cellAt(3, sumRowZ))
+	projectCost := costsTableInfo.totalsRange.sumFormula()
+	var topLevelRowsZ []int
+	for _, block := range tasksTableInfo.categoryBlocks {
+		rowZ := block.subtotalRowZ
+		if block.depth == 1 {
+			topLevelRowsZ = append(topLevelRowsZ, exc.rowZ)
+		}
+		exc.setValAndNext(strings.Repeat("  ", block.depth-1)+block.category, exc.headerStyleId)
+		exc.setFormulaAndNext(cellRange{exc.cellAt(tasksTableInfo.effortsColZ, rowZ), exc.cellAt(tasksTableInfo.effortsColZ+rolesCnt-1, rowZ)}.sumFormula())
+		exc.setFormulaAndNext(cellRange{exc.cellAt(tasksTableInfo.effortsWithRiskColZ, rowZ), exc.cellAt(tasksTableInfo.effortsWithRiskColZ+rolesCnt-1, rowZ)}.sumFormula())
+		directCell := exc.currentCell()
+		exc.setFormulaAndNext(exc.cellAt(tasksTableInfo.costColZ, rowZ), exc.currencyStyleId)
+		exc.setFormulaAndNext(fmt.Sprintf("IF(%s=0,0,%s/%s*%s)", directSumCell, directCell, directSumCell, projectCost), exc.currencyStyleId)
+		exc.cr()
+	}
+	exc.setValAndNext("Sum", exc.headerStyleId)
+	for colZ := 1; colZ <= 4; colZ++ {
+		style := exc.valueStyleId
+		if colZ >= 3 {
+			style = exc.currencyBoldStyleId
+		}
+		if len(topLevelRowsZ) == exc.rowZ-firstRowZ {
+			exc.setFormulaAndNext(cellRange{exc.cellAt(colZ, firstRowZ), exc.cellAt(colZ, exc.rowZ-1)}.sumFormula(), style)
+			continue
+		}
+		var cells []string
+		for _, rowZ := range topLevelRowsZ {
+			cells = append(cells, exc.cellAt(colZ, rowZ))
+		}
+		exc.setFormulaAndNext(strings.Join(cells, "+"), style)
+	}
 	exc.cr()
-	return res
 }
 
 type resourceCostsCells struct {
-	effortsCell, effortsWithRisksCell, countCell string
+	effortsCell, effortsWithRisksCell, rateCell, velocityCell, countCell, totalCell string
 }
 
 type costsTableInfo struct {
-	costsData map[string]*resourceCostsCells
+	costsData     map[string]*resourceCostsCells
+	roleRateCells map[string]string // cost of an hour of efforts stated in tasks for a role
+	titlesRange   cellRange
+	totalsRange   cellRange
 }
 
-func generateCostsTable(exc *excelGenerator, project Project, tasksTableInfo tasksTableInfo, parametersTableInfo parametersTableInfo) costsTableInfo {
-	res := costsTableInfo{costsData: map[string]*resourceCostsCells{}}
-	generateCostsTableHeader(exc, project)
-	effortsRange := cellRange{}
-	effortsWithRiskRange := cellRange{}
-	totalsRange := cellRange{}
+func generateCostsTable(exc *excelGenerator, project Project, tasksTableInfo tasksTableInfo) costsTableInfo {
+	res := costsTableInfo{costsData: map[string]*resourceCostsCells{}, roleRateCells: map[string]string{}}
+	usesVelocity := project.UsesVelocity()
+	generateCostsTableHeader(exc, project, usesVelocity)
+
+	// cells of the velocity and count of all levels of a role are needed before we reach their rows
+	colCount := 4
+	if usesVelocity {
+		colCount++
+	}
 	for i, r := range project.Team {
-		exc.setValAndNext(r.Title, exc.headerStyleId)
-		isFirst := i == 0
-		isLast := i == len(project.Team)-1
-		if isFirst {
-			effortsRange.hCell = exc.currentCell()
+		rowZ := exc.rowZ + i
+		cells := &resourceCostsCells{
+			effortsCell:          exc.cellAt(1, rowZ),
+			effortsWithRisksCell: exc.cellAt(2, rowZ),
+			rateCell:             exc.cellAt(3, rowZ),
+			countCell:            exc.cellAt(colCount, rowZ),
+			totalCell:            exc.cellAt(colCount+1, rowZ),
 		}
-		if isLast {
-			effortsRange.vCell = exc.currentCell()
+		if usesVelocity {
+			cells.velocityCell = exc.cellAt(4, rowZ)
 		}
-		res.costsData[r.Id] = &resourceCostsCells{effortsCell: exc.currentCell()}
-		var effortsFormula string
-		if r.Formula == "" {
-			effortsFormula = tasksTableInfo.cellRanges[r.Id].sumFormula()
-		} else {
-			formula := r.Formula
-			for _, r1 := range project.TeamExcludingDerived() {
+		res.costsData[r.Id] = cells
+		if r.Formula == "" && r.Level() == "" && !usesVelocity {
+			res.roleRateCells[r.Id] = cells.rateCell
+		}
+	}
+
+	effortsFormula := func(r Resource, cellRanges map[string]*cellRange) string {
+		var formula string
+		if r.Formula != "" {
+			formula = r.Formula
+			for _, r1 := range project.WorkRoles() {
 				rIdRe := regexp.MustCompile("\\b" + r1.Id + "\\b")
-				formula = rIdRe.ReplaceAllString(formula, "SUM("+tasksTableInfo.cellRanges[r1.Id].String()+")")
+				formula = rIdRe.ReplaceAllString(formula, tasksTableInfo.includedSum(*cellRanges[r1.Id]))
 			}
-			effortsFormula = formula
-		}
-		if project.AcceptancePercent > 0 {
-			effortsFormula += "*(1+" + parametersTableInfo.acceptancePercentCell + ")"
-		}
-		exc.setFormulaAndNext(effortsFormula)
-		if isFirst {
-			effortsWithRiskRange.hCell = exc.currentCell()
-		}
-		if isLast {
-			effortsWithRiskRange.vCell = exc.currentCell()
-		}
-		effortsWithRisksCell := exc.currentCell()
-		res.costsData[r.Id].effortsWithRisksCell = effortsWithRisksCell
-		var effortsWithRisksFormula string
-		if r.Formula == "" {
-			effortsWithRisksFormula = tasksTableInfo.cellRangesWithRisk[r.Id].sumFormula()
+		} else if r.Level() != "" {
+			formula = tasksTableInfo.includedSum(*cellRanges[r.Role()]) + "*" + res.costsData[r.Id].countCell +
+				"/(" + capacityFormula(project, r.Role(), res) + ")"
 		} else {
-			formula := r.Formula
-			for _, r1 := range project.TeamExcludingDerived() {
-				rIdRe := regexp.MustCompile("\\b" + r1.Id + "\\b")
-				formula = rIdRe.ReplaceAllString(formula, "SUM("+tasksTableInfo.cellRangesWithRisk[r1.Id].String()+")")
+			formula = tasksTableInfo.includedSum(*cellRanges[r.Id])
+			if usesVelocity {
+				formula += "/" + res.costsData[r.Id].velocityCell
 			}
-			effortsWithRisksFormula = formula
-		}
-		if project.AcceptancePercent > 0 {
-			effortsWithRisksFormula += "*(1+" + parametersTableInfo.acceptancePercentCell + ")"
 		}
-		exc.setFormulaAndNext(effortsWithRisksFormula)
-		rateCell := exc.currentCell()
+		return acceptanceFormula(formula)
+	}
+
+	firstRow := project.Team[0].Id
+	lastRow := project.Team[len(project.Team)-1].Id
+	for _, r := range project.Team {
+		cells := res.costsData[r.Id]
+		exc.setValAndNext(r.Title, exc.headerStyleId)
+		exc.setFormulaAndNext(effortsFormula(r, tasksTableInfo.cellRanges))
+		exc.setFormulaAndNext(effortsFormula(r, tasksTableInfo.cellRangesWithRisk))
 		exc.setValAndNext(r.Rate, exc.currencyStyleId)
-		res.costsData[r.Id].countCell = exc.currentCell()
-		exc.setValAndNext(r.Count)
-		if isFirst {
-			totalsRange.hCell = exc.currentCell()
-		}
-		if isLast {
-			totalsRange.vCell = exc.currentCell()
+		if usesVelocity {
+			if r.Formula == "" {
+				exc.setValAndNext(r.velocity())
+			} else {
+				exc.setValAndNext("")
+			}
 		}
-		exc.setFormulaAndNext(fmt.Sprintf("%d*%s*%s",
-			project.TimeUnit.ToHours(), effortsWithRisksCell, rateCell), exc.currencyStyleId)
+		exc.setValAndNext(r.Count)
+		exc.setFormulaAndNext(fmt.Sprintf("%s*%s*%s",
+			nameHoursPerUnit, cells.effortsWithRisksCell, cells.rateCell), exc.currencyStyleId)
 		exc.cr()
 	}
+	res.titlesRange = cellRange{exc.cellAt(0, exc.rowZ-len(project.Team)), exc.cellAt(0, exc.rowZ-1)}
+	res.totalsRange = cellRange{res.costsData[firstRow].totalCell, res.costsData[lastRow].totalCell}
 	exc.setValAndNext("Sum", exc.headerStyleId)
-	exc.setFormulaAndNext(effortsRange.sumFormula())
-	exc.setFormulaAndNext(effortsWithRiskRange.sumFormula())
+	exc.setFormulaAndNext(cellRange{res.costsData[firstRow].effortsCell, res.costsData[lastRow].effortsCell}.sumFormula())
+	exc.setFormulaAndNext(cellRange{res.costsData[firstRow].effortsWithRisksCell, res.costsData[lastRow].effortsWithRisksCell}.sumFormula())
 	exc.setValAndNext("")
+	if usesVelocity {
+		exc.setValAndNext("")
+	}
 	exc.setValAndNext("")
-	exc.setFormulaAndNext(totalsRange.sumFormula(), exc.currencyBoldStyleId)
+	exc.setFormulaAndNext(res.totalsRange.sumFormula(), exc.currencyBoldStyleId)
 	exc.cr()
 	return res
 }
 
-func generateCostsTableHeader(exc *excelGenerator, project Project) {
-	generateHeader(exc, []headerCell{
+// capacityFormula sums count*velocity of all seniority levels of a role
+func capacityFormula(project Project, role string, costsTableInfo costsTableInfo) string {
+	var parts []string
+	for _, r := range project.ResourcesOfRole(role) {
+		cells := costsTableInfo.costsData[r.Id]
+		parts = append(parts, cells.countCell+"*"+cells.velocityCell)
+	}
+	return strings.Join(parts, "+")
+}
+
+func generateCostsTableHeader(exc *excelGenerator, project Project, usesVelocity bool) {
+	cols := []headerCell{
 		{title: ""},
 		{title: fmt.Sprintf("Efforts (%vs)", project.TimeUnit)},
 		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit)},
 		{title: "Rate"},
+	}
+	if usesVelocity {
+		cols = append(cols, headerCell{title: "Velocity"})
+	}
+	cols = append(cols,
+		headerCell{title: "Team"},
+		headerCell{title: ColTotal},
+	)
+	generateHeader(exc, cols)
+}
+
+// generateBlendedRatesTable shows the cost of an hour of efforts stated in tasks for the roles
+// split into seniority levels, along with the effect of the levels on the duration
+func generateBlendedRatesTable(exc *excelGenerator, project Project, tasksTableInfo tasksTableInfo, costsTableInfo costsTableInfo) {
+	generateHeader(exc, []headerCell{
+		{title: ""},
+		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit)},
 		{title: "Team"},
+		{title: "Blended rate"},
 		{title: ColTotal},
+		{title: "Duration", mergedCells: 1},
 	})
+	for _, role := range project.WorkRoles() {
+		var countCells, totalCells, durations []string
+		for _, r := range project.ResourcesOfRole(role.Id) {
+			cells := costsTableInfo.costsData[r.Id]
+			countCells = append(countCells, cells.countCell)
+			totalCells = append(totalCells, cells.totalCell)
+			durations = append(durations, cells.effortsWithRisksCell+"/"+cells.countCell)
+		}
+		exc.setValAndNext(role.Title, exc.headerStyleId)
+		effortsCell := exc.currentCell()
+		exc.setFormulaAndNext(acceptanceFormula(tasksTableInfo.includedSum(*tasksTableInfo.cellRangesWithRisk[role.Id])))
+		exc.setFormulaAndNext(strings.Join(countCells, "+"))
+		costsTableInfo.roleRateCells[role.Id] = exc.currentCell()
+		totalCell := exc.cellAt(exc.colZ+1, exc.rowZ)
+		exc.setFormulaAndNext(fmt.Sprintf("%s/(%s*%s)", totalCell, nameHoursPerUnit, effortsCell), exc.currencyStyleId)
+		exc.setFormulaAndNext(strings.Join(totalCells, "+"), exc.currencyStyleId)
+		exc.setFormulaAndNext(fmt.Sprintf("ROUND(MAX(%s)*%s,1)", strings.Join(durations, ","), unitsToMonthsFormula))
+		exc.setValAndNext("Months")
+		exc.cr()
+	}
 }
 
 func generateDurationsTable(exc *excelGenerator, project Project, costsTableInfo costsTableInfo) {
@@ -376,7 +891,7 @@ func durationFormula(project Project, costsTableInfo costsTableInfo, f func(*res
 			sb.WriteString(",")
 		}
 	}
-	sb.WriteString(fmt.Sprintf(")*%d/%d/%d,1)", project.TimeUnit.ToHours(), WorkingHoursADay, WorkingDaysInMonth))
+	sb.WriteString(")*" + unitsToMonthsFormula + ",1)")
 	return sb.String()
 }
 
@@ -387,34 +902,60 @@ func generateDurationsTableHeader(exc *excelGenerator) {
 	})
 }
 
-func risksFormula(risks map[string]float64, valCell string, risksCell string) string {
-	// =ROUNDUP(D6*SWITCH($F6,"",1, "Low", 1.1, "Medium", 1.5, "High", 2, "Extreme", 5))
+// risksLookupFormula is the compatible version of risksFormula looking up the risk factor in the risks table
+func risksLookupFormula(parametersTableInfo parametersTableInfo, valCell string, risksCell string) string {
+	if len(parametersTableInfo.riskLabels) == 0 {
+		return noRisksFormula(valCell)
+	}
+	// =ROUNDUP(D6*IF($F6="",1,INDEX(RiskFactors,MATCH($F6,RiskNames,0))),0)
+	return fmt.Sprintf("ROUNDUP(%s*IF(%s=\"\",1,INDEX(%s,MATCH(%s,%s,0))),0)",
+		valCell, risksCell, nameRiskFactors, risksCell, nameRiskNames)
+}
+
+// includeFormula makes the efforts with risks of the task 0 unless the task is included
+func includeFormula(includeCell string, formula string) string {
+	return fmt.Sprintf("IF(%s=\"%s\",%s,0)", includeCell, includeYes, formula)
+}
+
+const unitsToMonthsFormula = nameHoursPerUnit + "/" + nameHoursPerDay + "/" + nameDaysPerMonth
+
+// noRisksFormula is the efforts with risks when the project has no risks to refer to
+func noRisksFormula(valCell string) string {
+	return fmt.Sprintf("ROUNDUP(%s,0)", valCell)
+}
+
+func risksFormula(parametersTableInfo parametersTableInfo, valCell string, risksCell string) string {
+	if len(parametersTableInfo.riskLabels) == 0 {
+		return noRisksFormula(valCell)
+	}
+	// =ROUNDUP(D6*SWITCH($F6,"",1, "low", Risk_low, "medium", Risk_medium, ...),0)
 	var sb strings.Builder
 	sb.WriteString("ROUNDUP(")
 	sb.WriteString(valCell)
 	sb.WriteString("*_xlfn.SWITCH(")
 	sb.WriteString(risksCell)
 	sb.WriteString(",\"\",1")
-	for k, v := range risks {
+	for _, k := range parametersTableInfo.riskLabels {
 		sb.WriteString(",\"")
 		sb.WriteString(k)
 		sb.WriteString("\",")
-		sb.WriteString(fmt.Sprintf("%f", v))
+		sb.WriteString(parametersTableInfo.riskFactorNames[k])
 	}
-	sb.WriteString("))")
-	//fmt.Println(sb.String())
+	sb.WriteString("),0)")
 	return sb.String()
 }
 
 func generateTasksTableHeader(exc *excelGenerator, project Project) {
-	teamExcludingDerived := project.TeamExcludingDerived()
+	workRoles := project.WorkRoles()
 
 	generateHeader(exc, []headerCell{
 		{title: ""},
 		{title: "", mergedCells: 1},
-		{title: fmt.Sprintf("Dev Efforts (%vs)", project.TimeUnit), mergedCells: len(teamExcludingDerived) - 1},
+		{title: fmt.Sprintf("Dev Efforts (%vs)", project.TimeUnit), mergedCells: len(workRoles) - 1},
+		{title: ""},
+		{title: ""},
+		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit), mergedCells: len(workRoles) - 1},
 		{title: ""},
-		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit), mergedCells: len(teamExcludingDerived) - 1},
 	})
 
 	cols := []headerCell{
@@ -422,16 +963,18 @@ func generateTasksTableHeader(exc *excelGenerator, project Project) {
 		{title: "Story", mergedCells: 1},
 	}
 
-	for _, r := range teamExcludingDerived {
+	for _, r := range workRoles {
 		cols = append(cols, headerCell{title: r.Title})
 	}
 
-	cols = append(cols, headerCell{title: "Risks"})
+	cols = append(cols, headerCell{title: "Risks"}, headerCell{title: "Include"})
 
-	for _, r := range teamExcludingDerived {
+	for _, r := range workRoles {
 		cols = append(cols, headerCell{title: r.Title})
 	}
 
+	cols = append(cols, headerCell{title: "Direct cost"})
+
 	generateHeader(exc, cols)
 }
 
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// chartSpec is the subset of the excelize chart format we use
+type chartSpec struct {
+	Type      string         `json:"type"`
+	Series    []chartSeries  `json:"series"`
+	Title     chartTitle     `json:"title"`
+	Legend    chartLegend    `json:"legend"`
+	Dimension chartDimension `json:"dimension"`
+	XAxis     chartAxis      `json:"x_axis"`
+	PlotArea  chartPlotArea  `json:"plotarea"`
+}
+
+type chartSeries struct {
+	Name       string `json:"name"`
+	Categories string `json:"categories"`
+	Values     string `json:"values"`
+}
+
+type chartTitle struct {
+	Name string `json:"name"`
+}
+
+type chartLegend struct {
+	None     bool   `json:"none"`
+	Position string `json:"position"`
+}
+
+type chartDimension struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type chartAxis struct {
+	ReverseOrder bool `json:"reverse_order"`
+}
+
+type chartPlotArea struct {
+	ShowPercent bool `json:"show_percent"`
+	ShowVal     bool `json:"show_val"`
+}
+
+func (exc *excelGenerator) addChart(cell string, chart chartSpec) {
+	if chart.Dimension == (chartDimension{}) {
+		chart.Dimension = chartDimension{Width: 640, Height: 360}
+	}
+	if chart.Legend.Position == "" {
+		chart.Legend.Position = "bottom"
+	}
+	format, err := json.Marshal(chart)
+	if err != nil {
+		exc.check(err)
+		return
+	}
+	exc.check(exc.wb.addChart(exc.sheet, cell, string(format)))
+}
+
+// sheetRef makes absolute reference to the cell or range of the sheet, like 'Sheet 1'!$A$1:$A$5
+func sheetRef(sheet string, cellOrRange string) string {
+	var parts []string
+	for _, cell := range strings.Split(strings.ReplaceAll(cellOrRange, "$", ""), ":") {
+		parts = append(parts, absCell(cell))
+	}
+	return "'" + strings.ReplaceAll(sheet, "'", "''") + "'!" + strings.Join(parts, ":")
+}
+
+const SheetCharts = "Charts"
+
+// generateChartsSheet puts the charts built on the cells of the main sheet, so that they follow the edits
+func generateChartsSheet(exc *excelGenerator, project Project, tasksTableInfo tasksTableInfo, costsTableInfo costsTableInfo) {
+	mainSheet := exc.sheet
+	exc.newSheet(SheetCharts)
+
+	exc.addChart("E1", chartSpec{
+		Type:  "pie",
+		Title: chartTitle{Name: "Cost by role"},
+		Series: []chartSeries{{
+			Name:       ColTotal,
+			Categories: sheetRef(mainSheet, costsTableInfo.titlesRange.String()),
+			Values:     sheetRef(mainSheet, costsTableInfo.totalsRange.String()),
+		}},
+		PlotArea: chartPlotArea{ShowPercent: true},
+	})
+
+	generateHeader(exc, []headerCell{
+		{title: "Category"},
+		{title: fmt.Sprintf("With Risks (%vs)", project.TimeUnit)},
+	})
+	firstRowZ := exc.rowZ
+	rolesCnt := len(project.WorkRoles())
+	for _, block := range tasksTableInfo.categoryBlocks {
+		// the subtotal row, the efforts of the nested categories are in it
+		withRisks := cellRange{cellName(tasksTableInfo.effortsWithRiskColZ, block.subtotalRowZ), cellName(tasksTableInfo.effortsWithRiskColZ+rolesCnt-1, block.subtotalRowZ)}
+		exc.setValAndNext(block.category)
+		exc.setFormulaAndNext("SUM(" + sheetRef(mainSheet, withRisks.String()) + ")")
+		exc.cr()
+	}
+	exc.addChart("E20", chartSpec{
+		Type:  "bar",
+		Title: chartTitle{Name: "Efforts by category"},
+		Series: []chartSeries{{
+			Name:       sheetRef(exc.sheet, exc.cellAt(1, firstRowZ-1)),
+			Categories: sheetRef(exc.sheet, cellRange{exc.cellAt(0, firstRowZ), exc.cellAt(0, exc.rowZ-1)}.String()),
+			Values:     sheetRef(exc.sheet, cellRange{exc.cellAt(1, firstRowZ), exc.cellAt(1, exc.rowZ-1)}.String()),
+		}},
+		Legend: chartLegend{None: true},
+		XAxis:  chartAxis{ReverseOrder: true},
+	})
+	exc.cr()
+
+	generateHeader(exc, []headerCell{
+		{title: ""},
+		{title: fmt.Sprintf("Efforts (%vs)", project.TimeUnit)},
+		{title: fmt.Sprintf("Risks (%vs)", project.TimeUnit)},
+	})
+	firstRowZ = exc.rowZ
+	for _, r := range project.Team {
+		cells := costsTableInfo.costsData[r.Id]
+		exc.setValAndNext(r.Title)
+		exc.setFormulaAndNext(sheetRef(mainSheet, cells.effortsCell))
+		exc.setFormulaAndNext(sheetRef(mainSheet, cells.effortsWithRisksCell) + "-" + sheetRef(mainSheet, cells.effortsCell))
+		exc.cr()
+	}
+	titles := sheetRef(exc.sheet, cellRange{exc.cellAt(0, firstRowZ), exc.cellAt(0, exc.rowZ-1)}.String())
+	var series []chartSeries
+	for colZ := 1; colZ <= 2; colZ++ {
+		series = append(series, chartSeries{
+			Name:       sheetRef(exc.sheet, exc.cellAt(colZ, firstRowZ-1)),
+			Categories: titles,
+			Values:     sheetRef(exc.sheet, cellRange{exc.cellAt(colZ, firstRowZ), exc.cellAt(colZ, exc.rowZ-1)}.String()),
+		})
+	}
+	exc.addChart("E39", chartSpec{
+		Type:   "barStacked",
+		Title:  chartTitle{Name: "Efforts with risks by role"},
+		Series: series,
+		XAxis:  chartAxis{ReverseOrder: true},
+	})
+
+	fitColWidths(exc)
+}
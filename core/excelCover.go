@@ -0,0 +1,73 @@
+package core
+
+import (
+	"fmt"
+	"math"
+)
+
+const SheetCover = "Cover"
+
+// hasCoverSheet tells if the workbook starts with the cover sheet, the model sheet follows it then
+func (opts ExcelOptions) hasCoverSheet(project Project) bool {
+	return opts.has(ExcelCoverSheet) && len(project.Sections()) > 0
+}
+
+// firstSheetName is the sheet the workbook is created with
+func (opts ExcelOptions) firstSheetName(project Project) string {
+	if opts.hasCoverSheet(project) {
+		return SheetCover
+	}
+	return opts.sheetName()
+}
+
+// generateCoverSheet fills the first sheet with the totals and the assumptions, exclusions and notes of the project,
+// the part of the quote that goes along with the numbers. The cost follows the edits of the model if it has the costs table.
+func generateCoverSheet(exc *excelGenerator, project Project, costsTableInfo costsTableInfo) {
+	exc.sheet, exc.colZ, exc.rowZ = SheetCover, 0, 0
+	name := project.Name
+	if name == "" {
+		name = "Project"
+	}
+	exc.setVal(name, exc.titleStyleId)
+	exc.mergeNext(1)
+	exc.cr()
+	exc.cr()
+
+	result := project.Calculate()
+	if project.Author != "" {
+		exc.setValAndNext("Author", exc.headerStyleId)
+		exc.setValAndNext(project.Author)
+		exc.cr()
+	}
+	if project.RateCard != "" {
+		exc.setValAndNext("Rate card", exc.headerStyleId)
+		exc.setValAndNext(project.RateCard)
+		exc.cr()
+	}
+	exc.setValAndNext("Cost", exc.headerStyleId)
+	if exc.opts.has(ExcelCostsTable) {
+		exc.setFormulaAndNext("SUM("+sheetRef(exc.opts.sheetName(), costsTableInfo.totalsRange.String())+")", exc.currencyBoldStyleId)
+	} else {
+		exc.setValAndNext(result.Cost, exc.currencyBoldStyleId)
+	}
+	exc.cr()
+	exc.setValAndNext(fmt.Sprintf("Efforts with risks (%vs)", project.TimeUnit), exc.headerStyleId)
+	exc.setValAndNext(math.Round(result.EffortsWithRisks*10) / 10)
+	exc.cr()
+	exc.setValAndNext("Duration with risks (mths)", exc.headerStyleId)
+	exc.setValAndNext(math.Round(result.DurationWithRisks*10) / 10)
+	exc.cr()
+
+	for _, section := range project.Sections() {
+		exc.cr()
+		exc.generateHeader(exc.headerStyleId, []headerCell{{title: section.Title, mergedCells: 1}})
+		for i, item := range section.Items {
+			exc.setValAndNext(i+1, exc.valueCenteredStyleId)
+			exc.setValAndNext(item, exc.textStyleId)
+			exc.cr()
+		}
+	}
+
+	exc.check(exc.wb.setColWidth(exc.sheet, "A", 28))
+	exc.check(exc.wb.setColWidth(exc.sheet, "B", 80))
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const SheetParameters = "Parameters"
+
+// names of the parameters cells, all the formulas of the model refer to them
+const (
+	nameHoursPerDay       = "HoursPerDay"
+	nameDaysPerWeek       = "DaysPerWeek"
+	nameDaysPerMonth      = "DaysPerMonth"
+	nameHoursPerUnit      = "HoursPerUnit"
+	nameAcceptancePercent = "AcceptancePercent"
+	nameRiskNames         = "RiskNames"
+	nameRiskFactors       = "RiskFactors"
+	nameIncludeValues     = "IncludeValues"
+	nameRiskFactorPrefix  = "Risk_" // followed by the risk label, the factor of the risk
+)
+
+// the values of the Include column of the tasks table, the tasks are summed up if it's includeYes
+const (
+	includeYes = "Yes"
+	includeNo  = "No"
+)
+
+type parametersTableInfo struct {
+	riskLabels      []string
+	riskFactorNames map[string]string // risk name -> the name of its factor cell
+}
+
+// generateParametersSheet puts the parameters of the model into their own sheet so that they can be tweaked in one place.
+// The current sheet and position are kept.
+func generateParametersSheet(exc *excelGenerator, project Project) parametersTableInfo {
+	res := parametersTableInfo{riskLabels: RiskLabels(project.Risks), riskFactorNames: map[string]string{}}
+	sheet, colZ, rowZ := exc.sheet, exc.colZ, exc.rowZ
+	exc.newSheet(SheetParameters)
+
+	generateHeader(exc, []headerCell{{title: "Parameter"}, {title: "Value"}})
+	parameter := func(title string, name string, val interface{}, styles ...int) {
+		exc.setValAndNext(title, exc.headerStyleId)
+		exc.defineName(name, exc.currentCell())
+		if formula, isFormula := val.(string); isFormula {
+			exc.setFormulaAndNext(formula, styles...)
+		} else {
+			exc.setValAndNext(val, styles...)
+		}
+		exc.cr()
+	}
+	parameter("Hours per day", nameHoursPerDay, WorkingHoursADay)
+	parameter("Days per week", nameDaysPerWeek, WorkingDaysInWeek)
+	parameter("Days per month", nameDaysPerMonth, WorkingDaysInMonth)
+	parameter(fmt.Sprintf("Hours per %v (time unit)", project.TimeUnit), nameHoursPerUnit, hoursPerUnitFormula(project.TimeUnit))
+	parameter("Cleanup & acceptance", nameAcceptancePercent, project.AcceptancePercent/100, exc.percentStyleId)
+	if project.RateCard != "" {
+		exc.setValAndNext("Rate card", exc.headerStyleId)
+		exc.setValAndNext(project.RateCard)
+		exc.cr()
+	}
+	exc.cr()
+
+	generateHeader(exc, []headerCell{{title: "Risk"}, {title: "Factor"}})
+	firstRowZ := exc.rowZ
+	for _, risk := range res.riskLabels {
+		exc.setValAndNext(risk, exc.headerStyleId)
+		res.riskFactorNames[risk] = riskFactorName(risk, res.riskFactorNames)
+		exc.defineName(res.riskFactorNames[risk], exc.currentCell())
+		exc.setValAndNext(project.Risks[risk])
+		exc.cr()
+	}
+	if len(res.riskLabels) > 0 {
+		exc.defineName(nameRiskNames, cellRange{exc.cellAt(0, firstRowZ), exc.cellAt(0, exc.rowZ-1)}.String())
+		exc.defineName(nameRiskFactors, cellRange{exc.cellAt(1, firstRowZ), exc.cellAt(1, exc.rowZ-1)}.String())
+	}
+	exc.cr()
+
+	generateHeader(exc, []headerCell{{title: "Include"}})
+	firstRowZ = exc.rowZ
+	for _, value := range []string{includeYes, includeNo} {
+		exc.setValAndNext(value)
+		exc.cr()
+	}
+	exc.defineName(nameIncludeValues, cellRange{exc.cellAt(0, firstRowZ), exc.cellAt(0, exc.rowZ-1)}.String())
+
+	fitColWidths(exc)
+	exc.sheet, exc.colZ, exc.rowZ = sheet, colZ, rowZ
+	return res
+}
+
+// riskFactorName makes the name for the factor of the risk, the characters not allowed in names are replaced
+// and the names the other risks have taken get the number
+func riskFactorName(risk string, taken map[string]string) string {
+	var sb strings.Builder
+	for _, c := range risk {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' {
+			sb.WriteRune(c)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	res := nameRiskFactorPrefix + sb.String()
+	for i, name := 2, res; ; i++ {
+		unique := true
+		for _, existing := range taken {
+			unique = unique && existing != name
+		}
+		if unique {
+			return name
+		}
+		name = fmt.Sprintf("%s_%d", res, i)
+	}
+}
+
+func hoursPerUnitFormula(timeUnit TimeUnit) string {
+	switch timeUnit {
+	case Day:
+		return nameHoursPerDay
+	case Week:
+		return nameDaysPerWeek + "*" + nameHoursPerDay
+	case Month:
+		return nameDaysPerMonth + "*" + nameHoursPerDay
+	default:
+		return fmt.Sprint(timeUnit.ToHours())
+	}
+}
+
+// defineName makes the workbook-wide name for the cell or range of the current sheet
+func (exc *excelGenerator) defineName(name string, cellOrRange string) {
+	exc.check(exc.wb.setDefinedName(name, sheetRef(exc.sheet, cellOrRange)))
+}
+
+// acceptanceFormula adds "Cleanup & acceptance" to the efforts formula
+func acceptanceFormula(formula string) string {
+	return formula + "*(1+" + nameAcceptancePercent + ")"
+}
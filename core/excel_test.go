@@ -0,0 +1,348 @@
+package core
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func generateAndOpen(t *testing.T, project Project, opts ExcelOptions) *excelize.File {
+	fileName := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := GenerateExcel(project, fileName, opts); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func formulasOf(t *testing.T, f *excelize.File, sheet string) []string {
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for rowZ, row := range rows {
+		for colZ := range row {
+			formula, err := f.GetCellFormula(sheet, cellName(colZ, rowZ))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formula != "" {
+				res = append(res, formula)
+			}
+		}
+	}
+	return res
+}
+
+func TestExcelCompatible(t *testing.T) {
+	project := mustNoError(t, projData)
+	f := generateAndOpen(t, project, ExcelOptions{Compatible: true})
+	formulas := formulasOf(t, f, "Sheet1")
+	if len(formulas) == 0 {
+		t.Fatalf("must be formulas")
+	}
+	lookups := 0
+	for _, formula := range formulas {
+		if strings.Contains(formula, "_xlfn") {
+			t.Fatalf("must not use new functions: %s", formula)
+		}
+		if strings.Contains(formula, "INDEX(RiskFactors,MATCH(") {
+			lookups++
+		}
+	}
+	if formula, _ := f.GetCellFormula("Sheet1", "I3"); formula != `IF(H3="Yes",ROUNDUP(D3*IF(G3="",1,INDEX(RiskFactors,MATCH(G3,RiskNames,0))),0),0)` {
+		t.Fatalf("wrong efforts with risks: %s", formula)
+	}
+	if lookups != len(project.Tasks)*len(project.WorkRoles()) {
+		t.Fatalf("every task and role must look up the risks: %d", lookups)
+	}
+	if sheet := sheetXml(t, project, ExcelOptions{Compatible: true}); !strings.Contains(sheet, `sqref="G3:G3" type="list"><formula1>RiskNames</formula1>`) {
+		t.Fatalf("the risks must have the drop list")
+	}
+	names := map[string]string{}
+	for _, name := range f.GetDefinedName() {
+		names[name.Name] = name.RefersTo
+	}
+	if names[nameRiskNames] != "'Parameters'!$A$9:$A$11" || names[nameRiskFactors] != "'Parameters'!$B$9:$B$11" {
+		t.Fatalf("wrong risks ranges: %v", names)
+	}
+}
+
+// sheetXml is the XML of the model sheet, for what excelize doesn't read back
+func sheetXml(t *testing.T, project Project, opts ExcelOptions) string {
+	return workbookPart(t, project, opts, "xl/worksheets/sheet1.xml")
+}
+
+// workbookPart gives the file of the generated workbook, like the XML of a sheet or a chart
+func workbookPart(t *testing.T, project Project, opts ExcelOptions, name string) string {
+	var buf bytes.Buffer
+	if err := GenerateExcelTo(&buf, project, opts); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range z.File {
+		if file.Name == name {
+			r, err := file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(data)
+		}
+	}
+	t.Fatalf("no %s", name)
+	return ""
+}
+
+func TestExcelWithoutRisks(t *testing.T) {
+	project := mustNoError(t, "team\nbe cnt=1 rate=40\ntasks\nAPI | Login | be=3\n")
+	project.Risks = map[string]float64{}
+	for _, opts := range []ExcelOptions{{}, {Compatible: true}} {
+		f := generateAndOpen(t, project, opts)
+		for _, formula := range formulasOf(t, f, DefaultSheetName) {
+			if strings.Contains(formula, "Risk") {
+				t.Fatalf("must not refer to the risks: %s", formula)
+			}
+		}
+		if sheet := sheetXml(t, project, opts); strings.Contains(sheet, nameRiskNames) {
+			t.Fatalf("must be no risks drop list")
+		}
+	}
+}
+
+func TestGenerateExcelTo(t *testing.T) {
+	project := mustNoError(t, projData)
+	var buf bytes.Buffer
+	err := GenerateExcelTo(&buf, project, ExcelOptions{
+		SheetName: "Estimate",
+		Styles:    ExcelStyles{HeaderColor: "#333333"},
+		Tables:    ExcelCostsTable | ExcelDurationsTable,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheets := f.GetSheetList()
+	if strings.Join(sheets, ",") != "Estimate,"+SheetParameters {
+		t.Fatalf("wrong sheets: %v", sheets)
+	}
+	if len(formulasOf(t, f, "Estimate")) == 0 {
+		t.Fatalf("must be formulas")
+	}
+}
+
+func TestGenerateExcelErrors(t *testing.T) {
+	project := mustNoError(t, projData)
+	for _, opts := range []ExcelOptions{
+		{SheetName: SheetParameters},
+		{SheetName: "a/b"},
+		{Tables: ExcelChartsSheet},
+	} {
+		if err := GenerateExcelTo(&bytes.Buffer{}, project, opts); err == nil {
+			t.Fatalf("must be error for %+v", opts)
+		}
+	}
+	project.Tasks = nil
+	if err := GenerateExcelTo(&bytes.Buffer{}, project, ExcelOptions{}); err == nil {
+		t.Fatalf("must be error for no tasks")
+	}
+}
+
+func TestCoverSheet(t *testing.T) {
+	project := mustNoError(t, projWithSections)
+	f := generateAndOpen(t, project, ExcelOptions{})
+	sheets := f.GetSheetList()
+	if sheets[0] != SheetCover || sheets[1] != DefaultSheetName {
+		t.Fatalf("the cover must go first: %v", sheets)
+	}
+	rows, err := f.GetRows(SheetCover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text []string
+	for _, row := range rows {
+		text = append(text, strings.Join(row, "|"))
+	}
+	for _, expected := range []string{"Shop", "Author|sales@example.com", "Not included", "1|the support after the launch"} {
+		if !strings.Contains(strings.Join(text, "\n"), expected) {
+			t.Fatalf("must contain %q:\n%s", expected, strings.Join(text, "\n"))
+		}
+	}
+	if formulas := formulasOf(t, f, SheetCover); len(formulas) != 1 || !strings.Contains(formulas[0], "'Sheet1'!") {
+		t.Fatalf("the cost must refer to the model: %v", formulas)
+	}
+
+	f = generateAndOpen(t, project, ExcelOptions{Tables: ExcelCostsTable})
+	if sheets := f.GetSheetList(); sheets[0] != DefaultSheetName {
+		t.Fatalf("must be no cover: %v", sheets)
+	}
+}
+
+func TestOptionalTasks(t *testing.T) {
+	project := mustNoError(t, `
+currency usd
+time_unit day
+team
+be cnt=1 rate=40
+qa cnt=1 rate=20 formula=be*0.5
+tasks
+API | Login  | be=3 risks=high
+API | Export | be=5 optional
+UI  | Theme  | be=2 optional=false
+`)
+	if !project.Tasks[1].Optional || project.Tasks[2].Optional {
+		t.Fatalf("wrong optional: %+v", project.Tasks)
+	}
+	result := project.Calculate()
+	f := generateAndOpen(t, project, ExcelOptions{Compatible: true, Tables: ExcelCostsTable})
+	rows, err := f.GetRows(DefaultSheetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var includes []string
+	for _, rowZ := range []int{2, 3, 5} { // the subtotal row of API goes before UI
+		includes = append(includes, rows[rowZ][5])
+	}
+	if strings.Join(includes, ",") != "Yes,No,Yes" {
+		t.Fatalf("wrong Include column: %v", includes)
+	}
+	// the formulas referring to the Parameters sheet are beyond the excelize calculation, the efforts as stated are not
+	subtotal, err := f.CalcCellValue(DefaultSheetName, "D5")
+	if err != nil || subtotal != "3" {
+		t.Fatalf("the optional task must not be in the subtotal: %s %v", subtotal, err)
+	}
+	for _, formula := range formulasOf(t, f, DefaultSheetName) {
+		if strings.Contains(formula, "ROUNDUP") && !strings.HasPrefix(formula, `IF(F`) {
+			t.Fatalf("the efforts with risks must respect Include: %s", formula)
+		}
+		if strings.Contains(formula, "SUBTOTAL") {
+			t.Fatalf("the sums must respect Include: %s", formula)
+		}
+	}
+	if result.Cost != (3*2+2)*8*40+(3*2+2)*0.5*8*20 {
+		t.Fatalf("the optional task must not be in the cost: %v", result.Cost)
+	}
+	mustBeError(t, "team\nbe cnt=1\ntasks\nAPI | Login | be=1 optional=maybe\n")
+}
+
+func TestParametersSheet(t *testing.T) {
+	project := mustNoError(t, projData)
+	f := generateAndOpen(t, project, ExcelOptions{})
+	names := map[string]string{}
+	for _, name := range f.GetDefinedName() {
+		names[name.Name] = name.RefersTo
+	}
+	for name, refersTo := range map[string]string{
+		nameHoursPerDay:       "'Parameters'!$B$2",
+		nameAcceptancePercent: "'Parameters'!$B$6",
+		nameRiskNames:         "'Parameters'!$A$9:$A$11",
+		"Risk_low":            "'Parameters'!$B$9",
+		"Risk_high":           "'Parameters'!$B$11",
+	} {
+		if names[name] != refersTo {
+			t.Fatalf("%s must refer to %s: %v", name, refersTo, names)
+		}
+	}
+	for cell, expected := range map[string]string{"B2": "8", "A9": "low", "B10": "1.5", "B11": "2"} {
+		if value, _ := f.GetCellValue(SheetParameters, cell); value != expected {
+			t.Fatalf("wrong %s: %s", cell, value)
+		}
+	}
+	formula, _ := f.GetCellFormula(DefaultSheetName, "I3")
+	if formula != `IF(H3="Yes",ROUNDUP(D3*_xlfn.SWITCH(G3,"",1,"low",Risk_low,"medium",Risk_medium,"high",Risk_high),0),0)` {
+		t.Fatalf("wrong efforts with risks: %s", formula)
+	}
+	for _, formula := range formulasOf(t, f, DefaultSheetName) {
+		if strings.Contains(formula, SheetParameters) {
+			t.Fatalf("the parameters must be referred by names: %s", formula)
+		}
+	}
+
+	taken := map[string]string{}
+	for _, risk := range []string{"very-high", "very_high", "very high"} {
+		taken[risk] = riskFactorName(risk, taken)
+	}
+	if taken["very-high"] != "Risk_very_high" || taken["very_high"] != "Risk_very_high_2" || taken["very high"] != "Risk_very_high_3" {
+		t.Fatalf("the names must be unique: %v", taken)
+	}
+}
+
+func TestCategoriesTableSumsUpToCost(t *testing.T) {
+	project := mustNoError(t, projData)
+	f := generateAndOpen(t, project, ExcelOptions{Tables: ExcelCostsTable | ExcelCategoriesTable})
+	var shares []string
+	for _, formula := range formulasOf(t, f, DefaultSheetName) {
+		if strings.HasPrefix(formula, "IF($D$") {
+			shares = append(shares, formula)
+		}
+	}
+	// the direct cost of the category with its share of the derived roles and acceptance, of the total of the costs table
+	if len(shares) != 2 || shares[0] != "IF($D$21=0,0,D19/$D$21*SUM(F11:F15))" {
+		t.Fatalf("wrong category totals: %v", shares)
+	}
+	if sum, _ := f.GetCellFormula(DefaultSheetName, "E21"); sum != "SUM(E19:E20)" {
+		t.Fatalf("wrong sum of the category totals: %s", sum)
+	}
+}
+
+func TestChartsSheet(t *testing.T) {
+	project := mustNoError(t, projData)
+	f := generateAndOpen(t, project, ExcelOptions{})
+	if f.GetSheetIndex(SheetCharts) < 0 {
+		t.Fatalf("no Charts sheet: %v", f.GetSheetList())
+	}
+	// the helper table of the efforts by category refers to the subtotal rows of the model
+	for cell, expected := range map[string]string{
+		"A2": "Initial", "B2": "SUM('Sheet1'!$I$5:$K$5)",
+		"A3": "API", "B3": "SUM('Sheet1'!$I$8:$K$8)",
+		"A7": "Back dev", "B7": "'Sheet1'!$B$12", "C7": "'Sheet1'!$C$12-'Sheet1'!$B$12",
+	} {
+		actual, _ := f.GetCellFormula(SheetCharts, cell)
+		if actual == "" {
+			actual, _ = f.GetCellValue(SheetCharts, cell)
+		}
+		if actual != expected {
+			t.Fatalf("wrong %s: %s", cell, actual)
+		}
+	}
+	for _, row := range []string{"B5", "B8"} {
+		if value, _ := f.GetCellValue(DefaultSheetName, row); value != "Subtotal" {
+			t.Fatalf("%s must be the subtotal: %s", row, value)
+		}
+	}
+	// the charts are built on the cells, not on the values
+	for chart, ref := range map[string]string{
+		"xl/charts/chart1.xml": "&#39;Sheet1&#39;!$F$11:$F$15",
+		"xl/charts/chart2.xml": "&#39;Charts&#39;!$B$2:$B$3",
+		"xl/charts/chart3.xml": "&#39;Charts&#39;!$C$6:$C$10",
+	} {
+		if xml := workbookPart(t, project, ExcelOptions{}, chart); !strings.Contains(xml, "<f>"+ref+"</f>") {
+			t.Fatalf("%s must refer to %s", chart, ref)
+		}
+	}
+}
+
+func TestSensitivitySheetRange(t *testing.T) {
+	project := mustNoError(t, projData)
+	f := generateAndOpen(t, project, ExcelOptions{Tables: ExcelCostsTable | ExcelSensitivitySheet, SensitivityRange: 0.5})
+	if value, _ := f.GetCellValue(SheetSensitivity, "D1"); value != "±50%" {
+		t.Fatalf("the sheet must take the range: %s", value)
+	}
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// formatLine is a line of the project text, the cells of the team and tasks lines are aligned in columns
+type formatLine struct {
+	text    string   // the line as is when there are no cells
+	cells   []string // the last one is not padded
+	sep     string   // between the cells
+	section int      // the team or tasks section the cells are aligned within
+}
+
+// FormatProject lays out the project text the standard way: one space between the values of directives
+// and team members, team ids and task columns aligned, single blank lines between the blocks.
+// Comments are kept. The project must parse, it's not validated otherwise.
+func FormatProject(projData string) (string, error) {
+	if _, err := parseProj(projData); err != nil {
+		return "", err
+	}
+	var lines []formatLine
+	mode := pmDirectives
+	section := 0
+	for _, text := range strings.Split(projData, "\n") {
+		line, _ := lexLine(text) // the project parses, so does the line
+		tokens := line.tokens
+		first := line.first()
+		if len(tokens) == 0 {
+			lines = append(lines, formatLine{text: line.comment})
+			continue
+		} else if mode == pmTasks && isContinuation(text, line) {
+			// before the keywords, like the parser, the indented line is the note whatever it says
+			lines = append(lines, formatLine{text: withComment("    "+joinRaw(tokens), line.comment)})
+			continue
+		} else if len(tokens) == 1 && (tokens[0].raw == "tasks" || tokens[0].raw == "team") {
+			mode = pmTeam
+			if tokens[0].raw == "tasks" {
+				mode = pmTasks
+			}
+			section++
+			lines = append(lines, formatLine{text: withComment(tokens[0].raw, line.comment)})
+			continue
+		} else if len(tokens) == 1 && isSectionKey(tokens[0].raw) {
+			mode = pmSection
+			lines = append(lines, formatLine{text: withComment(tokens[0].raw, line.comment)})
+			continue
+		} else if first == includeKey && !line.hasPipe() {
+			lines = append(lines, formatLine{text: withComment(joinRaw(tokens), line.comment)})
+			continue
+		} else if first == scenarioKey && !line.hasPipe() {
+			mode = pmScenario
+		}
+		switch mode {
+		case pmSection:
+			// the items are free text
+			lines = append(lines, formatLine{text: withComment(joinRawWithSpaces(tokens), line.comment)})
+		case pmTasks:
+			cells := line.cells()
+			lines = append(lines, formatLine{
+				cells:   []string{joinRaw(cells[0]), joinRaw(cells[1]), withComment(joinRaw(cells[2]), line.comment)},
+				sep:     " | ",
+				section: section,
+			})
+		case pmTeam:
+			lines = append(lines, formatLine{cells: []string{tokens[0].raw, withComment(joinRaw(tokens[1:]), line.comment)}, sep: " ", section: section})
+		default:
+			var formatted string
+			if mode == pmScenario && first != excludeKey && line.hasPipe() {
+				// the task added by the scenario
+				var cells []string
+				for _, cell := range line.cells() {
+					cells = append(cells, joinRaw(cell))
+				}
+				formatted = strings.Join(cells, " | ")
+			} else if first == excludeKey {
+				cells := lexedLine{tokens: tokens[1:]}.cells()
+				var selector []string
+				for _, cell := range cells {
+					selector = append(selector, joinRaw(cell))
+				}
+				formatted = excludeKey + " " + strings.Join(selector, " | ")
+			} else if directive, found := directives[first]; (found && directive.directiveType == DtSingleValue) || first == scenarioKey {
+				// the value may have spaces of its own, like the project name
+				formatted = tokens[0].raw + " " + joinRawWithSpaces(tokens[1:])
+			} else {
+				formatted = joinRaw(tokens)
+			}
+			lines = append(lines, formatLine{text: withComment(formatted, line.comment)})
+		}
+	}
+
+	widths := map[int][]int{} // section -> widths of the columns
+	for _, l := range lines {
+		for i, cell := range l.cells {
+			if len(widths[l.section]) <= i {
+				widths[l.section] = append(widths[l.section], 0)
+			}
+			if w := utf8.RuneCountInString(cell); w > widths[l.section][i] {
+				widths[l.section][i] = w
+			}
+		}
+	}
+
+	var res []string
+	for _, l := range lines {
+		text := l.text
+		if l.cells != nil {
+			var cells []string
+			for i, cell := range l.cells {
+				if i < len(l.cells)-1 {
+					cell += strings.Repeat(" ", widths[l.section][i]-utf8.RuneCountInString(cell))
+				}
+				cells = append(cells, cell)
+			}
+			text = strings.TrimRight(strings.Join(cells, l.sep), " ")
+		}
+		if text == "" && (len(res) == 0 || res[len(res)-1] == "") {
+			continue
+		}
+		res = append(res, text)
+	}
+	for len(res) > 0 && res[len(res)-1] == "" {
+		res = res[:len(res)-1]
+	}
+	return strings.Join(res, "\n") + "\n", nil
+}
+
+func withComment(text, comment string) string {
+	if comment == "" {
+		return text
+	}
+	return strings.TrimSpace(text + " " + comment)
+}
+
+// joinRawWithSpaces joins the words as written with the spaces they are separated by
+func joinRawWithSpaces(tokens []token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			b.WriteString(t.sep)
+		}
+		b.WriteString(t.raw)
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+)
+
+// evalFormula evaluates the formula of a derived resource, like "(be+fe)*0.3",
+// resolving the resource ids in it from vars
+func evalFormula(formula string, vars map[string]float64) (float64, error) {
+	fp := &formulaParser{src: []rune(formula), vars: vars}
+	res, err := fp.expr()
+	if err != nil {
+		return 0, err
+	}
+	fp.skipSpaces()
+	if fp.pos < len(fp.src) {
+		return 0, fmt.Errorf("unexpected '%c' at %d", fp.src[fp.pos], fp.pos+1)
+	}
+	return res, nil
+}
+
+type formulaParser struct {
+	src  []rune
+	pos  int
+	vars map[string]float64
+}
+
+func (fp *formulaParser) skipSpaces() {
+	for fp.pos < len(fp.src) && unicode.IsSpace(fp.src[fp.pos]) {
+		fp.pos++
+	}
+}
+
+// peek returns the next non-space char, 0 at the end
+func (fp *formulaParser) peek() rune {
+	fp.skipSpaces()
+	if fp.pos < len(fp.src) {
+		return fp.src[fp.pos]
+	}
+	return 0
+}
+
+func (fp *formulaParser) expr() (float64, error) {
+	res, err := fp.term()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := fp.peek()
+		if op != '+' && op != '-' {
+			return res, nil
+		}
+		fp.pos++
+		val, err := fp.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			res += val
+		} else {
+			res -= val
+		}
+	}
+}
+
+func (fp *formulaParser) term() (float64, error) {
+	res, err := fp.factor()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := fp.peek()
+		if op != '*' && op != '/' {
+			return res, nil
+		}
+		fp.pos++
+		val, err := fp.factor()
+		if err != nil {
+			return 0, err
+		}
+		if op == '*' {
+			res *= val
+		} else {
+			res /= val
+		}
+	}
+}
+
+func isFormulaIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.'
+}
+
+func (fp *formulaParser) factor() (float64, error) {
+	c := fp.peek()
+	switch {
+	case c == 0:
+		return 0, fmt.Errorf("unexpected end of formula")
+	case c == '-':
+		fp.pos++
+		val, err := fp.factor()
+		return -val, err
+	case c == '(':
+		fp.pos++
+		val, err := fp.expr()
+		if err != nil {
+			return 0, err
+		}
+		if fp.peek() != ')' {
+			return 0, fmt.Errorf("missing ')' at %d", fp.pos+1)
+		}
+		fp.pos++
+		return val, nil
+	case isFormulaIdentChar(c):
+		start := fp.pos
+		for fp.pos < len(fp.src) && isFormulaIdentChar(fp.src[fp.pos]) {
+			fp.pos++
+		}
+		token := string(fp.src[start:fp.pos])
+		if unicode.IsDigit(c) || c == '.' {
+			val, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return 0, fmt.Errorf("wrong number: %s", token)
+			}
+			return val, nil
+		}
+		val, exists := fp.vars[token]
+		if !exists {
+			return 0, fmt.Errorf("unknown resource: %s", token)
+		}
+		return val, nil
+	default:
+		return 0, fmt.Errorf("unexpected '%c' at %d", c, fp.pos+1)
+	}
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const htmlStyle = `body{font-family:sans-serif;max-width:60em;margin:2em auto}table{border-collapse:collapse}` +
+	`th,td{border:1px solid #ccc;padding:.3em .6em}td.num{text-align:right}`
+
+// WriteHtml writes the same report as WriteMarkdown as the standalone HTML page
+func WriteHtml(w io.Writer, project Project, result ProjectCalculationResult) error {
+	name := project.Name
+	if name == "" {
+		name = "Project"
+	}
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n",
+		html.EscapeString(name), htmlStyle)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", html.EscapeString(name))
+	if project.Author != "" {
+		fmt.Fprintf(w, "<p>Author: %s</p>\n", html.EscapeString(project.Author))
+	}
+	fmt.Fprintf(w, "<p>Cost: <b>%s</b>, efforts with risks: %.1f %ss, duration with risks: %.1f mths, team of %d.</p>\n",
+		html.EscapeString(project.Currency.Format(result.Cost)), result.EffortsWithRisks, project.TimeUnit, result.DurationWithRisks, teamSize(result.Team))
+
+	fmt.Fprintf(w, "<h2>Team</h2>\n<table>\n<tr><th>Resource</th><th>Count</th><th>Rate</th><th>Efforts with risks (%ss)</th><th>Cost</th></tr>\n", project.TimeUnit)
+	for _, r := range result.Resources {
+		title := r.Title
+		if title == "" {
+			title = r.Id
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td class=\"num\">%d</td><td class=\"num\">%s</td><td class=\"num\">%.1f</td><td class=\"num\">%s</td></tr>\n",
+			html.EscapeString(title), r.Count, html.EscapeString(project.Currency.Format(r.Rate)), r.EffortsWithRisks, html.EscapeString(project.Currency.Format(r.Cost)))
+	}
+	fmt.Fprintf(w, "</table>\n")
+
+	fmt.Fprintf(w, "<h2>Tasks</h2>\n<table>\n<tr><th>Category</th><th>Task</th><th>Risk</th><th>Efforts with risks (%ss)</th><th>Cost</th></tr>\n", project.TimeUnit)
+	withDetails := false
+	for _, task := range project.Tasks {
+		calc := project.CalculateTask(task, result)
+		title := html.EscapeString(task.Title)
+		if task.Optional {
+			title += " <i>(optional)</i>"
+		}
+		fmt.Fprintf(w, "<tr><td>%s</td><td>%s</td><td>%s</td><td class=\"num\">%.1f</td><td class=\"num\">%s</td></tr>\n",
+			html.EscapeString(task.Category), title, html.EscapeString(task.Risk), calc.EffortsWithRisks, html.EscapeString(project.Currency.Format(calc.Cost)))
+		withDetails = withDetails || task.HasDetails()
+	}
+	fmt.Fprintf(w, "</table>\n")
+
+	if tags := project.CalculateTags(result); len(project.Tags()) > 0 {
+		fmt.Fprintf(w, "<h2>By tag</h2>\n<table>\n<tr><th>Tag</th><th>Tasks</th><th>Efforts with risks (%ss)</th><th>Cost</th></tr>\n", project.TimeUnit)
+		for _, tc := range tags {
+			fmt.Fprintf(w, "<tr><td>%s</td><td class=\"num\">%d</td><td class=\"num\">%.1f</td><td class=\"num\">%s</td></tr>\n",
+				html.EscapeString(tagTitle(tc.Tag)), tc.Tasks, tc.EffortsWithRisks, html.EscapeString(project.Currency.Format(tc.Cost)))
+		}
+		fmt.Fprintf(w, "</table>\n")
+	}
+
+	if withDetails {
+		fmt.Fprintf(w, "<h2>Details</h2>\n")
+		for _, task := range project.Tasks {
+			if !task.HasDetails() {
+				continue
+			}
+			fmt.Fprintf(w, "<h3>%s / %s</h3>\n", html.EscapeString(task.Category), html.EscapeString(task.Title))
+			if task.Notes != "" {
+				fmt.Fprintf(w, "<p>%s</p>\n", strings.ReplaceAll(html.EscapeString(task.Notes), "\n", "<br>\n"))
+			}
+			writeHtmlList(w, "Assumptions", task.Assumptions)
+			writeHtmlList(w, "Out of scope", task.OutOfScope)
+		}
+	}
+
+	for _, section := range project.Sections() {
+		fmt.Fprintf(w, "<h2>%s</h2>\n<ol>\n", section.Title)
+		for _, item := range section.Items {
+			fmt.Fprintf(w, "<li>%s</li>\n", html.EscapeString(item))
+		}
+		fmt.Fprintf(w, "</ol>\n")
+	}
+	_, err := fmt.Fprintf(w, "</body>\n</html>\n")
+	return err
+}
+
+// writeHtmlList writes the lines of the text as the list under the title
+func writeHtmlList(w io.Writer, title, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(w, "<p><b>%s:</b></p>\n<ul>\n", title)
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "<li>%s</li>\n", html.EscapeString(line))
+	}
+	fmt.Fprintf(w, "</ul>\n")
+}
@@ -0,0 +1,340 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// includeRecord is the file to merge into the project at the place of the team and tasks it's included at
+type includeRecord struct {
+	pos    Pos
+	path   string // relative to the including file unless absolute
+	prefix string // of the categories of the included tasks
+	team   int    // the number of team members before the include
+	tasks  int    // the number of tasks before the include
+}
+
+const includePrefixKey = "prefix"
+
+// directives describing the included project on its own, they are not merged
+var ownDirectives = map[string]bool{directiveProject.name: true, directiveAuthor.name: true}
+
+func parseInclude(tokens []token, errors *ProjectParseError) (includeRecord, bool) {
+	if len(tokens) < 2 {
+		errors.addError("include should have a file")
+		return includeRecord{}, false
+	}
+	res := includeRecord{path: tokens[1].text}
+	for k, v := range parseKeyValPairs(tokens[2:], errors) {
+		if k != includePrefixKey {
+			errors.addError("Unknown include property: " + k)
+		}
+		res.prefix = strings.Trim(v, categorySeparator)
+	}
+	return res, true
+}
+
+// ProjectFromFile reads the project in the text format along with the files it includes.
+// It returns all the files read, the project file first.
+func ProjectFromFile(path string) (Project, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Project{}, []string{path}, err
+	}
+	return ProjectFromFileText(path, string(data))
+}
+
+// ProjectFromFileText is ProjectFromFile with the text of the project file given, like the unsaved one of an editor
+func ProjectFromFileText(path, projData string) (Project, []string, error) {
+	return projectFromFileText(path, projData, readFileText, ProjectFileOptions{})
+}
+
+// ProjectFileOptions change how ProjectFromFileTextWith reads the project
+type ProjectFileOptions struct {
+	Format   string    // "yaml" or "json" for the project in the form of ProjectFromYaml, the text format otherwise
+	RateCard *RateCard // taken instead of the one of the rate_card directive, that is not read then
+}
+
+// ProjectFromFileTextWith is ProjectFromFileText for the YAML and JSON projects as well, the rate card of the rate_card
+// directive is relative to the project file in any format
+func ProjectFromFileTextWith(path, projData string, opts ProjectFileOptions) (Project, []string, error) {
+	return projectFromFileText(path, projData, readFileText, opts)
+}
+
+func readFileText(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	return string(data), err
+}
+
+func projectFromFileText(path, projData string, read func(path string) (string, error), opts ProjectFileOptions) (Project, []string, error) {
+	path = filepath.Clean(path)
+	inc := &includer{read: read}
+	var projParsed projParsed
+	var err error
+	switch opts.Format {
+	case formatJson:
+		if err = checkJson(projData); err != nil {
+			return Project{}, []string{path}, err
+		}
+		fallthrough
+	case formatYaml:
+		// no includes in YAML
+		inc.files = []string{path}
+		projParsed, err = parseYaml(projData)
+	default:
+		projParsed, err = inc.parse(path, projData)
+	}
+	proj, err := projectWithScenarios(projParsed, err)
+	rateCard, hasRateCard := projParsed.directives[directiveRateCard.name]
+	if !hasRateCard && opts.RateCard == nil {
+		return proj, inc.files, err
+	}
+	errors := &ProjectParseError{}
+	errors.addOtherError(err)
+	errors.pos = rateCard.pos
+	card := opts.RateCard
+	if card == nil {
+		cardFile := rateCard.value
+		if !filepath.IsAbs(cardFile) {
+			from := rateCard.pos.File
+			if from == "" {
+				from = path // the positions of YAML have no file
+			}
+			cardFile = filepath.Join(filepath.Dir(from), cardFile)
+		}
+		inc.files = append(inc.files, cardFile)
+		fileCard, cardErr := readRateCard(cardFile, read)
+		if cardErr != nil {
+			errors.addErrorf("Can't read the rate card: %v", cardErr)
+		} else {
+			card = &fileCard
+		}
+	}
+	if card != nil {
+		var cardErr error
+		proj, cardErr = proj.WithRateCard(*card, time.Now())
+		errors.addOtherError(cardErr)
+	}
+	if errors.hasErrors() {
+		return proj, inc.files, errors
+	}
+	return proj, inc.files, nil
+}
+
+func readRateCard(path string, read func(path string) (string, error)) (RateCard, error) {
+	data, err := read(path)
+	if err != nil {
+		return RateCard{}, err
+	}
+	return rateCardNamed(path, data)
+}
+
+// includer parses the project file with the files it includes, each file is merged once per prefix
+type includer struct {
+	read     func(path string) (string, error)
+	stack    []string // the files being parsed, the outermost first
+	files    []string // all the files read
+	prefix   string   // of the categories of the file being parsed, the prefixes of the includes joined
+	included map[includedKey]bool
+}
+
+// includedKey is the file merged with the prefix, the same file under another prefix brings its tasks again
+type includedKey struct {
+	file, prefix string
+}
+
+func (inc *includer) parse(file, projData string) (projParsed, error) {
+	inc.stack = append(inc.stack, file)
+	if !containsString(inc.files, file) {
+		inc.files = append(inc.files, file)
+	}
+	if inc.included == nil {
+		inc.included = map[includedKey]bool{}
+	}
+	inc.included[includedKey{file, inc.prefix}] = true
+	defer func() { inc.stack = inc.stack[:len(inc.stack)-1] }()
+
+	own, err := parseProjFile(projData, file)
+	errors := &ProjectParseError{}
+	errors.addOtherError(err)
+	res := own
+	res.includes = nil
+	res.team = []resourceRecord{}
+	res.tasksRecords = []taskRecord{}
+	res.directives = map[string]directiveVals{}
+	res.sections = map[string][]string{}
+	for k, v := range own.sections {
+		res.sections[k] = append([]string{}, v...)
+	}
+	for k, v := range own.directives {
+		res.directives[k] = v
+	}
+	ownTeam := map[string]bool{}
+	for _, r := range own.team {
+		ownTeam[r.id] = true
+	}
+
+	teamFrom, tasksFrom := 0, 0
+	for _, include := range own.includes {
+		res.team = append(res.team, own.team[teamFrom:include.team]...)
+		res.tasksRecords = append(res.tasksRecords, own.tasksRecords[tasksFrom:include.tasks]...)
+		teamFrom, tasksFrom = include.team, include.tasks
+
+		sub, found := inc.include(file, include, errors)
+		if !found {
+			continue
+		}
+		errors.pos = include.pos
+		res.mergeDirectives(sub.directives, file, errors)
+		for _, r := range sub.team {
+			if ownTeam[r.id] {
+				continue // the including file overrides the team member
+			}
+			if i := res.teamMember(r.id); i < 0 {
+				res.team = append(res.team, r)
+			} else if !sameProps(res.team[i].resourceProps, r.resourceProps) {
+				errors.addErrorf("Team member %s differs in %s and %s", r.id, res.team[i].pos.File, r.pos.File)
+			}
+		}
+		for _, task := range sub.tasksRecords {
+			task.category = include.withPrefix(task.category)
+			res.tasksRecords = append(res.tasksRecords, task)
+		}
+		for _, key := range sectionKeys {
+			for _, item := range sub.sections[key] {
+				if !containsString(res.sections[key], item) {
+					res.sections[key] = append(res.sections[key], item)
+				}
+			}
+		}
+		for _, scenario := range sub.scenarios {
+			for _, existing := range res.scenarios {
+				if existing.name == scenario.name {
+					errors.addError("Duplicating scenario: " + scenario.name)
+				}
+			}
+			var exclusions []taskSelector
+			for _, selector := range scenario.exclusions {
+				exclusions = append(exclusions, taskSelector{category: include.withPrefix(selector.category), title: selector.title})
+			}
+			scenario.exclusions = exclusions
+			var tasks []taskRecord
+			for _, task := range scenario.tasks {
+				task.category = include.withPrefix(task.category)
+				tasks = append(tasks, task)
+			}
+			scenario.tasks = tasks
+			res.scenarios = append(res.scenarios, scenario)
+		}
+	}
+	res.team = append(res.team, own.team[teamFrom:]...)
+	res.tasksRecords = append(res.tasksRecords, own.tasksRecords[tasksFrom:]...)
+
+	errors.pos = Pos{}
+	if !errors.hasErrors() {
+		return res, nil
+	}
+	return res, errors
+}
+
+// include parses the included file, false if it's not found, makes a cycle or is already included with the same prefix
+func (inc *includer) include(from string, include includeRecord, errors *ProjectParseError) (projParsed, bool) {
+	errors.pos = include.pos
+	file := include.path
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(filepath.Dir(from), file)
+	}
+	file = filepath.Clean(file)
+	for i, f := range inc.stack {
+		if f == file {
+			errors.addError("Include cycle: " + strings.Join(append(append([]string{}, inc.stack[i:]...), file), " -> "))
+			return projParsed{}, false
+		}
+	}
+	prefix := inc.prefix
+	if include.prefix != "" {
+		prefix = includeRecord{prefix: inc.prefix}.withPrefix(include.prefix)
+	}
+	if inc.included[includedKey{file, prefix}] {
+		// like the shared team included by several parts
+		return projParsed{}, false
+	}
+	projData, err := inc.read(file)
+	if err != nil {
+		errors.addErrorf("Can't include %s: %v", include.path, err)
+		return projParsed{}, false
+	}
+	outerPrefix := inc.prefix
+	inc.prefix = prefix
+	sub, err := inc.parse(file, projData)
+	inc.prefix = outerPrefix
+	errors.addOtherError(err)
+	return sub, true
+}
+
+func (include includeRecord) withPrefix(category string) string {
+	if include.prefix == "" {
+		return category
+	}
+	return include.prefix + categorySeparator + category
+}
+
+// mergeDirectives takes the directives of the included file unless the including file has them.
+// The included files must agree on them, the risks are merged by label.
+func (projParsed *projParsed) mergeDirectives(included map[string]directiveVals, file string, errors *ProjectParseError) {
+	for name, v := range included {
+		existing, exists := projParsed.directives[name]
+		switch {
+		case ownDirectives[name]:
+		case !exists:
+			projParsed.directives[name] = v
+		case existing.pos.File == file:
+			if v.directiveType == DtKeyVal {
+				existing.values = mergeProps(v.values, existing.values)
+				projParsed.directives[name] = existing
+			}
+		case v.directiveType == DtKeyVal:
+			for k, val := range v.values {
+				if existingVal, found := existing.values[k]; found && existingVal != val {
+					errors.addErrorf("%s %s differs in %s and %s: %s and %s", name, k, existing.pos.File, v.pos.File, existingVal, val)
+				}
+			}
+			existing.values = mergeProps(v.values, existing.values)
+			projParsed.directives[name] = existing
+		case existing.value != v.value:
+			errors.addErrorf("%s differs in %s and %s: %s and %s", name, existing.pos.File, v.pos.File, existing.value, v.value)
+		}
+	}
+}
+
+func (projParsed projParsed) teamMember(id string) int {
+	for i, r := range projParsed.team {
+		if r.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameProps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, exists := b[k]; !exists || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
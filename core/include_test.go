@@ -0,0 +1,135 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func readFrom(files map[string]string) func(path string) (string, error) {
+	return func(path string) (string, error) {
+		text, exists := files[path]
+		if !exists {
+			return "", errors.New("no such file")
+		}
+		return text, nil
+	}
+}
+
+func TestInclude(t *testing.T) {
+	files := map[string]string{
+		"shared/team.txt": `
+currency usd
+time_unit day
+risks medium=1.5 high=2
+team
+be cnt=2 rate=40
+fe cnt=1 rate=30
+`,
+		"api.txt": `
+project Billing API
+include shared/team.txt
+tasks
+Auth | Login | be=3 risks=high
+`,
+		"web.txt": `
+include shared/team.txt
+tasks
+Pages | Invoices | fe=5
+scenario lean
+exclude Pages
+`,
+	}
+	proj, read, err := projectFromFileText("main.txt", `
+project Program
+risks high=3
+include api.txt prefix=Billing
+tasks
+Release | Launch | be=1
+include web.txt prefix=Web
+team
+fe cnt=2 rate=35
+`, readFrom(files), ProjectFileOptions{})
+	if err != nil {
+		t.Fatalf("must parse: %v", err)
+	}
+	if strings.Join(read, ",") != "main.txt,api.txt,shared/team.txt,web.txt" {
+		t.Fatalf("wrong files read: %v", read)
+	}
+	if proj.Name != "Program" || proj.Currency != Usd || proj.Risks["high"] != 3 || proj.Risks["medium"] != 1.5 {
+		t.Fatalf("wrong directives: %+v", proj)
+	}
+	if len(proj.Team) != 2 || proj.ResourceById("fe").Rate != 35 {
+		t.Fatalf("the own team member must override the included one: %v", proj.Team)
+	}
+	var tasks []string
+	for _, task := range proj.Tasks {
+		tasks = append(tasks, task.Category+"|"+task.Title)
+	}
+	if strings.Join(tasks, ",") != "Billing/Auth|Login,Release|Launch,Web/Pages|Invoices" {
+		t.Fatalf("wrong tasks: %v", tasks)
+	}
+	if proj.Tasks[0].Pos != (Pos{File: "api.txt", Line: 5}) {
+		t.Fatalf("wrong task position: %v", proj.Tasks[0].Pos)
+	}
+	if len(proj.Scenarios) != 1 || len(proj.Scenarios[0].Project.Tasks) != 2 {
+		t.Fatalf("the scenario of the included file must exclude the prefixed category: %v", proj.Scenarios)
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	files := map[string]string{
+		"a.txt": "include b.txt\n",
+		"b.txt": "include a.txt\n",
+		"c.txt": "currency eur\nteam\nbe cnt=1 rate=x\n",
+		"d.txt": "currency usd\n",
+	}
+	_, _, err := projectFromFileText("a.txt", files["a.txt"], readFrom(files), ProjectFileOptions{})
+	if err == nil || !strings.Contains(err.Error(), "b.txt:1: Include cycle: a.txt -> b.txt -> a.txt") {
+		t.Fatalf("must report the cycle: %v", err)
+	}
+
+	_, _, err = projectFromFileText("main.txt", "include c.txt\ninclude d.txt\ninclude e.txt\ntasks\nA | B | be=1\n", readFrom(files), ProjectFileOptions{})
+	if err == nil {
+		t.Fatalf("must be errors")
+	}
+	for _, expected := range []string{
+		"c.txt:3: Wrong rate value for be: x",
+		"main.txt:2: currency differs in c.txt and d.txt: eur and usd",
+		"main.txt:3: Can't include e.txt: no such file",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("must report %q: %v", expected, err)
+		}
+	}
+
+	mustBeError(t, "include c.txt\n")
+	mustBeError(t, "include\n")
+	if _, _, err := projectFromFileText("main.txt", "include d.txt title=x\n", readFrom(files), ProjectFileOptions{}); err == nil {
+		t.Fatalf("must reject unknown include properties")
+	}
+}
+
+func TestIncludeWithPrefixes(t *testing.T) {
+	files := map[string]string{
+		"team.txt": "time_unit day\nteam\nbe cnt=1 rate=40\n",
+		"part.txt": "include team.txt\ntasks\nAPI | Login | be=2\n",
+	}
+	proj, read, err := projectFromFileText("main.txt", "include part.txt prefix=Web\ninclude part.txt prefix=Mobile\ninclude part.txt prefix=Web\n", readFrom(files), ProjectFileOptions{})
+	if err != nil {
+		t.Fatalf("must parse: %v", err)
+	}
+	if strings.Join(read, ",") != "main.txt,part.txt,team.txt" {
+		t.Fatalf("wrong files read: %v", read)
+	}
+	var tasks []string
+	for _, task := range proj.Tasks {
+		tasks = append(tasks, task.Category+"|"+task.Title)
+	}
+	if strings.Join(tasks, ",") != "Web/API|Login,Mobile/API|Login" || len(proj.Team) != 1 {
+		t.Fatalf("the file must be merged once per prefix: %v %v", tasks, proj.Team)
+	}
+	if cost := proj.Calculate().Cost; cost != 2*2*8*40 {
+		t.Fatalf("wrong cost: %v", cost)
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"errors"
+	"strings"
+)
+
+type tokenKind int8
+
+const (
+	tokWord tokenKind = iota
+	tokPipe
+)
+
+// token is a word or a cell separator of the line of the text format
+type token struct {
+	kind tokenKind
+	text string // with the quotes and escapes resolved
+	raw  string // as written
+	sep  string // the spaces before the token
+}
+
+// lexedLine is the line split into tokens. Words are separated by spaces and tabs except the ones in double
+// quotes, `|` separates the cells of the task rows. A backslash escapes the next character, like `\|` or `\"`.
+// `#` starts the comment unless it's inside a word, like in `title=C#`.
+type lexedLine struct {
+	tokens  []token
+	comment string // with the leading #, "" if none
+}
+
+func lexLine(line string) (lexedLine, error) {
+	var res lexedLine
+	var text, raw, sep strings.Builder
+	inWord, quoted, escaped := false, false, false
+	endWord := func() {
+		if inWord {
+			res.tokens = append(res.tokens, token{kind: tokWord, text: text.String(), raw: raw.String(), sep: sep.String()})
+			text.Reset()
+			raw.Reset()
+			sep.Reset()
+			inWord = false
+		}
+	}
+	for i, r := range line {
+		switch {
+		case escaped:
+			text.WriteRune(r)
+			raw.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			inWord = true
+			raw.WriteRune(r)
+			escaped = true
+		case r == '"':
+			inWord = true
+			raw.WriteRune(r)
+			quoted = !quoted
+		case quoted:
+			text.WriteRune(r)
+			raw.WriteRune(r)
+		case r == ' ' || r == '\t' || r == '\r':
+			endWord()
+			sep.WriteRune(r)
+		case r == '|':
+			endWord()
+			res.tokens = append(res.tokens, token{kind: tokPipe, text: "|", raw: "|", sep: sep.String()})
+			sep.Reset()
+		case r == '#' && !inWord:
+			res.comment = strings.TrimRight(line[i:], " \t\r")
+			return res, nil
+		default:
+			inWord = true
+			text.WriteRune(r)
+			raw.WriteRune(r)
+		}
+	}
+	if quoted {
+		return res, errors.New("unterminated quote")
+	}
+	if escaped {
+		return res, errors.New("nothing to escape at the end of the line")
+	}
+	endWord()
+	return res, nil
+}
+
+// cells splits the tokens by the pipes
+func (l lexedLine) cells() [][]token {
+	res := [][]token{nil}
+	for _, t := range l.tokens {
+		if t.kind == tokPipe {
+			res = append(res, nil)
+		} else {
+			res[len(res)-1] = append(res[len(res)-1], t)
+		}
+	}
+	return res
+}
+
+func (l lexedLine) hasPipe() bool {
+	for _, t := range l.tokens {
+		if t.kind == tokPipe {
+			return true
+		}
+	}
+	return false
+}
+
+// first is the text of the first token, "" for the empty line
+func (l lexedLine) first() string {
+	if len(l.tokens) == 0 {
+		return ""
+	}
+	return l.tokens[0].text
+}
+
+// joinText joins the texts of the words with the spaces they are separated by, like the name of the project
+func joinText(tokens []token) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			b.WriteString(t.sep)
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// joinRaw joins the words as written with single spaces
+func joinRaw(tokens []token) string {
+	var raws []string
+	for _, t := range tokens {
+		raws = append(raws, t.raw)
+	}
+	return strings.Join(raws, " ")
+}
+
+// quoteIfNeeded makes the value a single word of the text format
+func quoteIfNeeded(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"\\|#") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown writes the estimate for people to read: the totals, the team, the tasks with their cost (the optional
+// ones are not in the totals), the cost by tag if tagged, the details of the tasks that have notes, assumptions
+// or out of scope, then the assumptions, exclusions and notes of the project
+func WriteMarkdown(w io.Writer, project Project, result ProjectCalculationResult) error {
+	name := project.Name
+	if name == "" {
+		name = "Project"
+	}
+	fmt.Fprintf(w, "# %s\n\n", mdEscape(name))
+	if project.Author != "" {
+		fmt.Fprintf(w, "Author: %s\n\n", mdEscape(project.Author))
+	}
+	fmt.Fprintf(w, "Cost: **%s**, efforts with risks: %.1f %ss, duration with risks: %.1f mths, team of %d.\n\n",
+		project.Currency.Format(result.Cost), result.EffortsWithRisks, project.TimeUnit, result.DurationWithRisks, teamSize(result.Team))
+
+	fmt.Fprintf(w, "## Team\n\n| Resource | Count | Rate | Efforts with risks (%ss) | Cost |\n|---|--:|--:|--:|--:|\n", project.TimeUnit)
+	for _, r := range result.Resources {
+		title := r.Title
+		if title == "" {
+			title = r.Id
+		}
+		fmt.Fprintf(w, "| %s | %d | %s | %.1f | %s |\n", mdEscape(title), r.Count, project.Currency.Format(r.Rate),
+			r.EffortsWithRisks, project.Currency.Format(r.Cost))
+	}
+
+	fmt.Fprintf(w, "\n## Tasks\n\n| Category | Task | Risk | Efforts with risks (%ss) | Cost |\n|---|---|---|--:|--:|\n", project.TimeUnit)
+	withDetails := false
+	for _, task := range project.Tasks {
+		calc := project.CalculateTask(task, result)
+		title := mdEscape(task.Title)
+		if task.Optional {
+			title += " _(optional)_"
+		}
+		fmt.Fprintf(w, "| %s | %s | %s | %.1f | %s |\n", mdEscape(task.Category), title, task.Risk,
+			calc.EffortsWithRisks, project.Currency.Format(calc.Cost))
+		withDetails = withDetails || task.HasDetails()
+	}
+
+	if tags := project.CalculateTags(result); len(project.Tags()) > 0 {
+		fmt.Fprintf(w, "\n## By tag\n\n| Tag | Tasks | Efforts with risks (%ss) | Cost |\n|---|--:|--:|--:|\n", project.TimeUnit)
+		for _, tc := range tags {
+			fmt.Fprintf(w, "| %s | %d | %.1f | %s |\n", mdEscape(tagTitle(tc.Tag)), tc.Tasks, tc.EffortsWithRisks, project.Currency.Format(tc.Cost))
+		}
+	}
+
+	if withDetails {
+		fmt.Fprintf(w, "\n## Details\n")
+		for _, task := range project.Tasks {
+			if !task.HasDetails() {
+				continue
+			}
+			fmt.Fprintf(w, "\n### %s / %s\n", mdEscape(task.Category), mdEscape(task.Title))
+			if task.Notes != "" {
+				fmt.Fprintf(w, "\n%s\n", mdParagraph(task.Notes))
+			}
+			writeMarkdownList(w, "Assumptions", task.Assumptions)
+			writeMarkdownList(w, "Out of scope", task.OutOfScope)
+		}
+	}
+
+	for _, section := range project.Sections() {
+		fmt.Fprintf(w, "\n## %s\n\n", section.Title)
+		for i, item := range section.Items {
+			fmt.Fprintf(w, "%d. %s\n", i+1, mdEscape(item))
+		}
+	}
+	return nil
+}
+
+// writeMarkdownList writes the lines of the text as the list under the title
+func writeMarkdownList(w io.Writer, title, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(w, "\n**%s:**\n\n", title)
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(w, "- %s\n", mdEscape(line))
+	}
+}
+
+// mdParagraph keeps the line breaks of the text
+func mdParagraph(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, mdEscape(line))
+	}
+	return strings.Join(lines, "  \n")
+}
+
+var mdReplacer = strings.NewReplacer(`\`, `\\`, `|`, `\|`, `*`, `\*`, `_`, `\_`, "`", "\\`", `<`, `&lt;`)
+
+func mdEscape(s string) string {
+	return mdReplacer.Replace(s)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"github.com/xuri/excelize/v2"
+	"strings"
+	"testing"
+)
+
+const projWithNotes = `
+project Shop
+currency usd
+time_unit day
+team
+be cnt=1 rate=40
+tasks
+API | Login | be=3 note="OAuth with Google"
+    and GitHub
+    assumption the client registers the apps
+    assumption two providers
+    out_of_scope SSO
+API | Orders | be=5
+`
+
+func TestTaskNotes(t *testing.T) {
+	project := mustNoError(t, projWithNotes)
+	task := project.Tasks[0]
+	if task.Notes != "OAuth with Google\nand GitHub" || task.Assumptions != "the client registers the apps\ntwo providers" ||
+		task.OutOfScope != "SSO" || len(task.Work) != 1 {
+		t.Fatalf("wrong notes: %+v", task)
+	}
+	if project.Tasks[1].HasDetails() {
+		t.Fatalf("must be no details: %+v", project.Tasks[1])
+	}
+	mustBeError(t, "team\nbe cnt=1 rate=40\ntasks\n  the note without task\n")
+
+	formatted, err := FormatProject(projWithNotes)
+	if err != nil || !strings.Contains(formatted, "API | Login  | be=3 note=\"OAuth with Google\"\n    and GitHub\n    assumption the client") {
+		t.Fatalf("the notes must be kept: %s %v", formatted, err)
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateExcelTo(&buf, project, ExcelOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments := f.GetComments()[DefaultSheetName]
+	if len(comments) != 1 || !strings.Contains(comments[0].Text, "Assumptions:\nthe client registers the apps") {
+		t.Fatalf("wrong comments: %+v", comments)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	project := mustNoError(t, projWithNotes)
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, project, project.Calculate()); err != nil {
+		t.Fatal(err)
+	}
+	md := buf.String()
+	for _, expected := range []string{
+		"# Shop\n",
+		"| API | Login |  | 3.0 | $960 |",
+		"### API / Login\n\nOAuth with Google  \nand GitHub\n",
+		"**Out of scope:**\n\n- SSO\n",
+	} {
+		if !strings.Contains(md, expected) {
+			t.Fatalf("must contain %q:\n%s", expected, md)
+		}
+	}
+}
+
+func TestWriteMarkdownSections(t *testing.T) {
+	project := mustNoError(t, projWithSections)
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, project, project.Calculate()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\n## Not included\n\n1. the support after the launch\n") {
+		t.Fatalf("must contain the sections:\n%s", buf.String())
+	}
+}
+
+func TestWriteHtml(t *testing.T) {
+	project := mustNoError(t, projWithNotes+"exclusions\n- the <support>\n")
+	var buf bytes.Buffer
+	if err := WriteHtml(&buf, project, project.Calculate()); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, expected := range []string{
+		"<h1>Shop</h1>\n",
+		"<tr><td>API</td><td>Login</td><td></td><td class=\"num\">3.0</td><td class=\"num\">$960</td></tr>",
+		"<h3>API / Login</h3>\n<p>OAuth with Google<br>\nand GitHub</p>\n",
+		"<p><b>Out of scope:</b></p>\n<ul>\n<li>SSO</li>\n</ul>\n",
+		"<h2>Not included</h2>\n<ol>\n<li>the &lt;support&gt;</li>\n</ol>\n",
+		"</body>\n</html>\n",
+	} {
+		if !strings.Contains(page, expected) {
+			t.Fatalf("must contain %q:\n%s", expected, page)
+		}
+	}
+}
@@ -0,0 +1,589 @@
+package core
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// odsWorkbook keeps the cells in memory and writes them as OpenDocument spreadsheet.
+// Formulas are translated to OpenFormula, charts are not supported.
+type odsWorkbook struct {
+	sheets       []*odsSheet
+	styles       []*excelize.Style
+	definedNames map[string]string // name -> Excel reference
+	nameOrder    []string
+}
+
+type odsSheet struct {
+	name      string
+	cells     map[[2]int]*odsCell // {colZ, rowZ}
+	merges    map[[2]int][2]int   // top left -> {cols, rows} spanned
+	covered   map[[2]int]bool
+	colWidths map[int]float64
+	dropLists map[[2]int]string
+	levels    map[int]int // rowZ -> outline level
+	maxColZ   int
+	maxRowZ   int
+}
+
+type odsCell struct {
+	val     interface{}
+	formula string
+	styleId int
+	comment string
+}
+
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+func newOdsWorkbook(sheet string) *odsWorkbook {
+	res := &odsWorkbook{definedNames: map[string]string{}}
+	res.newSheet(sheet)
+	return res
+}
+
+func (o *odsWorkbook) sheet(name string) (*odsSheet, error) {
+	for _, s := range o.sheets {
+		if s.name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %s is not exist", name)
+}
+
+func (o *odsWorkbook) cell(sheet, cell string) (*odsCell, error) {
+	s, err := o.sheet(sheet)
+	if err != nil {
+		return nil, err
+	}
+	col, row, err := excelize.CellNameToCoordinates(cell)
+	if err != nil {
+		return nil, err
+	}
+	pos := [2]int{col - 1, row - 1}
+	if s.cells[pos] == nil {
+		s.cells[pos] = &odsCell{}
+	}
+	if pos[0] > s.maxColZ {
+		s.maxColZ = pos[0]
+	}
+	if pos[1] > s.maxRowZ {
+		s.maxRowZ = pos[1]
+	}
+	return s.cells[pos], nil
+}
+
+func (o *odsWorkbook) newSheet(name string) {
+	if _, err := o.sheet(name); err == nil {
+		return
+	}
+	o.sheets = append(o.sheets, &odsSheet{
+		name:      name,
+		cells:     map[[2]int]*odsCell{},
+		merges:    map[[2]int][2]int{},
+		covered:   map[[2]int]bool{},
+		colWidths: map[int]float64{},
+		dropLists: map[[2]int]string{},
+		levels:    map[int]int{},
+	})
+}
+func (o *odsWorkbook) newStyle(style *excelize.Style) (int, error) {
+	o.styles = append(o.styles, style)
+	return len(o.styles), nil
+}
+func (o *odsWorkbook) setCellStyle(sheet, cell string, styleId int) error {
+	c, err := o.cell(sheet, cell)
+	if err != nil {
+		return err
+	}
+	c.styleId = styleId
+	return nil
+}
+func (o *odsWorkbook) setCellValue(sheet, cell string, val interface{}) error {
+	c, err := o.cell(sheet, cell)
+	if err != nil {
+		return err
+	}
+	c.val, c.formula = val, ""
+	return nil
+}
+func (o *odsWorkbook) setCellFormula(sheet, cell, formula string) error {
+	c, err := o.cell(sheet, cell)
+	if err != nil {
+		return err
+	}
+	c.val, c.formula = nil, formula
+	return nil
+}
+func (o *odsWorkbook) mergeCell(sheet, hCell, vCell string) error {
+	s, err := o.sheet(sheet)
+	if err != nil {
+		return err
+	}
+	col0, row0, err := excelize.CellNameToCoordinates(hCell)
+	if err != nil {
+		return err
+	}
+	col1, row1, err := excelize.CellNameToCoordinates(vCell)
+	if err != nil {
+		return err
+	}
+	s.merges[[2]int{col0 - 1, row0 - 1}] = [2]int{col1 - col0 + 1, row1 - row0 + 1}
+	for colZ := col0 - 1; colZ < col1; colZ++ {
+		for rowZ := row0 - 1; rowZ < row1; rowZ++ {
+			if colZ != col0-1 || rowZ != row0-1 {
+				s.covered[[2]int{colZ, rowZ}] = true
+			}
+		}
+	}
+	return nil
+}
+func (o *odsWorkbook) addDropList(sheet, cell, name string) error {
+	s, err := o.sheet(sheet)
+	if err != nil {
+		return err
+	}
+	col, row, err := excelize.CellNameToCoordinates(cell)
+	if err != nil {
+		return err
+	}
+	s.dropLists[[2]int{col - 1, row - 1}] = name
+	return nil
+}
+func (o *odsWorkbook) setDefinedName(name, refersTo string) error {
+	if _, exists := o.definedNames[name]; !exists {
+		o.nameOrder = append(o.nameOrder, name)
+	}
+	o.definedNames[name] = refersTo
+	return nil
+}
+func (o *odsWorkbook) getCols(sheet string) ([][]string, error) {
+	s, err := o.sheet(sheet)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.cells) == 0 {
+		return nil, nil
+	}
+	res := make([][]string, s.maxColZ+1)
+	for colZ := range res {
+		res[colZ] = make([]string, s.maxRowZ+1)
+		for rowZ := range res[colZ] {
+			if c := s.cells[[2]int{colZ, rowZ}]; c != nil && c.val != nil {
+				res[colZ][rowZ] = fmt.Sprint(c.val)
+			}
+		}
+	}
+	return res, nil
+}
+func (o *odsWorkbook) setColWidth(sheet, col string, width float64) error {
+	s, err := o.sheet(sheet)
+	if err != nil {
+		return err
+	}
+	colNum, err := excelize.ColumnNameToNumber(col)
+	if err != nil {
+		return err
+	}
+	s.colWidths[colNum-1] = width
+	return nil
+}
+func (o *odsWorkbook) addChart(sheet, cell, format string) error {
+	return nil
+}
+
+func (o *odsWorkbook) addComment(sheet, cell, text string) error {
+	c, err := o.cell(sheet, cell)
+	if err != nil {
+		return err
+	}
+	c.comment = text
+	return nil
+}
+func (o *odsWorkbook) setRowOutlineLevel(sheet string, row int, level int) error {
+	s, err := o.sheet(sheet)
+	if err != nil {
+		return err
+	}
+	s.levels[row-1] = level
+	return nil
+}
+
+func (o *odsWorkbook) write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	// the mimetype goes first and uncompressed so that the format can be recognized by the leading bytes
+	mimeType, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(mimeType, odsMimeType); err != nil {
+		return err
+	}
+	for _, entry := range []struct{ name, content string }{
+		{"META-INF/manifest.xml", odsManifest},
+		{"styles.xml", odsStyles},
+		{"content.xml", o.content()},
+	} {
+		fw, err := zw.Create(entry.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(fw, entry.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+const odsManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="` + odsMimeType + `"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+ <manifest:file-entry manifest:full-path="styles.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`
+
+const odsNamespaces = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+	` xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0"` +
+	` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+	` xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"` +
+	` xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"` +
+	` xmlns:number="urn:oasis:names:tc:opendocument:xmlns:datastyle:1.0"` +
+	` xmlns:of="urn:oasis:names:tc:opendocument:xmlns:of:1.2"` +
+	` office:version="1.2"`
+
+const odsStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-styles ` + odsNamespaces + `><office:styles/></office:document-styles>
+`
+
+// odsValueType is the kind of values formatted by the style
+func (o *odsWorkbook) odsValueType(styleId int) string {
+	if styleId < 1 || styleId > len(o.styles) {
+		return "float"
+	}
+	style := o.styles[styleId-1]
+	if style.CustomNumFmt != nil && strings.HasPrefix(*style.CustomNumFmt, "[$") {
+		return "currency"
+	}
+	if style.NumFmt == 10 {
+		return "percentage"
+	}
+	return "float"
+}
+
+func (o *odsWorkbook) content() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	sb.WriteString(`<office:document-content ` + odsNamespaces + `>`)
+
+	sb.WriteString(`<office:automatic-styles>`)
+	for i, style := range o.styles {
+		writeOdsStyle(&sb, i+1, style)
+	}
+	for si, s := range o.sheets {
+		for colZ := 0; colZ <= s.maxColZ; colZ++ {
+			width, exists := s.colWidths[colZ]
+			if !exists {
+				continue
+			}
+			// a character of the default font is about 0.19cm wide
+			fmt.Fprintf(&sb, `<style:style style:name="co%d_%d" style:family="table-column">`+
+				`<style:table-column-properties style:column-width="%.3fcm"/></style:style>`, si, colZ, width*0.19)
+		}
+	}
+	sb.WriteString(`</office:automatic-styles>`)
+
+	sb.WriteString(`<office:body><office:spreadsheet>`)
+	validations := map[string]string{} // name of the list -> name of the validation
+	var validationsXml strings.Builder
+	for _, s := range o.sheets {
+		for _, pos := range s.dropListCells() {
+			name := s.dropLists[pos]
+			if _, exists := validations[name]; exists {
+				continue
+			}
+			validations[name] = fmt.Sprintf("val%d", len(validations)+1)
+			condition := "of:cell-content-is-in-list(" + strings.TrimPrefix(odsFormula(o.resolveName(name)), "of:=") + ")"
+			fmt.Fprintf(&validationsXml, `<table:content-validation table:name="%s" table:condition="%s" table:allow-empty-cell="true"`+
+				` table:display-list="unsorted" table:base-cell-address="%s"/>`,
+				validations[name], xmlEscape(condition), xmlEscape(odsAddress(sheetRef(s.name, cellName(pos[0], pos[1])))))
+		}
+	}
+	if validationsXml.Len() > 0 {
+		sb.WriteString(`<table:content-validations>` + validationsXml.String() + `</table:content-validations>`)
+	}
+
+	for si, s := range o.sheets {
+		fmt.Fprintf(&sb, `<table:table table:name="%s">`, xmlEscape(s.name))
+		for colZ := 0; colZ <= s.maxColZ; colZ++ {
+			if _, exists := s.colWidths[colZ]; exists {
+				fmt.Fprintf(&sb, `<table:table-column table:style-name="co%d_%d"/>`, si, colZ)
+			} else {
+				sb.WriteString(`<table:table-column/>`)
+			}
+		}
+		level := 0 // of the row groups open
+		for rowZ := 0; rowZ <= s.maxRowZ; rowZ++ {
+			for ; level < s.levels[rowZ]; level++ {
+				sb.WriteString(`<table:table-row-group>`)
+			}
+			for ; level > s.levels[rowZ]; level-- {
+				sb.WriteString(`</table:table-row-group>`)
+			}
+			sb.WriteString(`<table:table-row>`)
+			for colZ := 0; colZ <= s.maxColZ; colZ++ {
+				pos := [2]int{colZ, rowZ}
+				o.writeCell(&sb, s, pos, validations)
+			}
+			sb.WriteString(`</table:table-row>`)
+		}
+		sb.WriteString(strings.Repeat(`</table:table-row-group>`, level))
+		sb.WriteString(`</table:table>`)
+	}
+
+	if len(o.nameOrder) > 0 {
+		sb.WriteString(`<table:named-expressions>`)
+		for _, name := range o.nameOrder {
+			address := odsAddress(o.definedNames[name])
+			baseCell := strings.SplitN(address, ":", 2)[0]
+			fmt.Fprintf(&sb, `<table:named-range table:name="%s" table:base-cell-address="%s" table:cell-range-address="%s"/>`,
+				xmlEscape(name), xmlEscape(baseCell), xmlEscape(address))
+		}
+		sb.WriteString(`</table:named-expressions>`)
+	}
+	sb.WriteString(`</office:spreadsheet></office:body></office:document-content>`)
+	return sb.String()
+}
+
+// dropListCells lists the cells with drop lists row by row, so that the output is stable
+func (s *odsSheet) dropListCells() [][2]int {
+	var res [][2]int
+	for pos := range s.dropLists {
+		res = append(res, pos)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i][1] != res[j][1] {
+			return res[i][1] < res[j][1]
+		}
+		return res[i][0] < res[j][0]
+	})
+	return res
+}
+
+func (o *odsWorkbook) writeCell(sb *strings.Builder, s *odsSheet, pos [2]int, validations map[string]string) {
+	if s.covered[pos] {
+		sb.WriteString(`<table:covered-table-cell/>`)
+		return
+	}
+	c := s.cells[pos]
+	if c == nil {
+		c = &odsCell{}
+	}
+	sb.WriteString(`<table:table-cell`)
+	if c.styleId > 0 {
+		fmt.Fprintf(sb, ` table:style-name="ce%d"`, c.styleId)
+	}
+	if span, merged := s.merges[pos]; merged {
+		fmt.Fprintf(sb, ` table:number-columns-spanned="%d" table:number-rows-spanned="%d"`, span[0], span[1])
+	}
+	if name, exists := s.dropLists[pos]; exists {
+		fmt.Fprintf(sb, ` table:content-validation-name="%s"`, validations[name])
+	}
+	text := ""
+	if c.formula != "" {
+		// no cached value, the formula is calculated on open
+		fmt.Fprintf(sb, ` table:formula="%s"`, xmlEscape(odsFormula(c.formula)))
+	} else {
+		switch v := c.val.(type) {
+		case nil:
+		case string:
+			if v != "" {
+				sb.WriteString(` office:value-type="string"`)
+				text = v
+			}
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			valueType := o.odsValueType(c.styleId)
+			fmt.Fprintf(sb, ` office:value-type="%s" office:value="%v"`, valueType, v)
+			text = fmt.Sprint(v)
+		case bool:
+			fmt.Fprintf(sb, ` office:value-type="boolean" office:boolean-value="%v"`, v)
+			text = fmt.Sprint(v)
+		default:
+			sb.WriteString(` office:value-type="string"`)
+			text = fmt.Sprint(v)
+		}
+	}
+	if text == "" && c.comment == "" {
+		sb.WriteString(`/>`)
+		return
+	}
+	sb.WriteString(`>`)
+	if c.comment != "" {
+		sb.WriteString(`<office:annotation>`)
+		for _, line := range strings.Split(c.comment, "\n") {
+			sb.WriteString(`<text:p>` + xmlEscape(line) + `</text:p>`)
+		}
+		sb.WriteString(`</office:annotation>`)
+	}
+	if text != "" {
+		sb.WriteString(`<text:p>` + xmlEscape(text) + `</text:p>`)
+	}
+	sb.WriteString(`</table:table-cell>`)
+}
+
+func writeOdsStyle(sb *strings.Builder, styleId int, style *excelize.Style) {
+	dataStyle := ""
+	if style.CustomNumFmt != nil && strings.HasPrefix(*style.CustomNumFmt, "[$") {
+		// [$€]#,##0
+		symbol := strings.SplitN(strings.TrimPrefix(*style.CustomNumFmt, "[$"), "]", 2)[0]
+		dataStyle = fmt.Sprintf("N%d", styleId)
+		fmt.Fprintf(sb, `<number:currency-style style:name="%s"><number:currency-symbol>%s</number:currency-symbol>`+
+			`<number:number number:decimal-places="0" number:min-integer-digits="1" number:grouping="true"/></number:currency-style>`,
+			dataStyle, xmlEscape(symbol))
+	} else if style.NumFmt == 10 {
+		dataStyle = fmt.Sprintf("N%d", styleId)
+		fmt.Fprintf(sb, `<number:percentage-style style:name="%s">`+
+			`<number:number number:decimal-places="2" number:min-integer-digits="1"/><number:text>%%</number:text></number:percentage-style>`,
+			dataStyle)
+	}
+
+	fmt.Fprintf(sb, `<style:style style:name="ce%d" style:family="table-cell"`, styleId)
+	if dataStyle != "" {
+		fmt.Fprintf(sb, ` style:data-style-name="%s"`, dataStyle)
+	}
+	sb.WriteString(`><style:table-cell-properties`)
+	if len(style.Border) > 0 {
+		sb.WriteString(` fo:border="0.06pt solid #000000"`)
+	}
+	if style.Fill.Pattern == 1 && len(style.Fill.Color) > 0 {
+		fmt.Fprintf(sb, ` fo:background-color="%s"`, style.Fill.Color[0])
+	}
+	if style.Alignment != nil && style.Alignment.Vertical == "center" {
+		sb.WriteString(` style:vertical-align="middle"`)
+	} else if style.Alignment != nil && style.Alignment.Vertical == "top" {
+		sb.WriteString(` style:vertical-align="top"`)
+	}
+	if style.Alignment != nil && style.Alignment.WrapText {
+		sb.WriteString(` fo:wrap-option="wrap"`)
+	}
+	sb.WriteString(`/>`)
+	if style.Alignment != nil && style.Alignment.Horizontal == "center" {
+		sb.WriteString(`<style:paragraph-properties fo:text-align="center"/>`)
+	}
+	if style.Font != nil {
+		sb.WriteString(`<style:text-properties`)
+		if style.Font.Bold {
+			sb.WriteString(` fo:font-weight="bold"`)
+		}
+		if style.Font.Color != "" {
+			fmt.Fprintf(sb, ` fo:color="%s"`, style.Font.Color)
+		}
+		if style.Font.Size > 0 {
+			fmt.Fprintf(sb, ` fo:font-size="%gpt"`, style.Font.Size)
+		}
+		sb.WriteString(`/>`)
+	}
+	sb.WriteString(`</style:style>`)
+}
+
+// resolveName gives the reference of the defined name, names unknown are kept as is
+func (o *odsWorkbook) resolveName(name string) string {
+	if ref, exists := o.definedNames[name]; exists {
+		return ref
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	checkErr(xml.EscapeText(&sb, []byte(s)))
+	return sb.String()
+}
+
+// odsReferenceRe matches the Excel reference like A1, $A$1:$B$2 or 'Sheet 1'!$A$1, not followed by a name or a function call
+var odsReferenceRe = regexp.MustCompile(`^(?:('(?:[^']|'')+'|[A-Za-z_][A-Za-z0-9_.]*)!)?(\$?[A-Z]{1,3}\$?[0-9]+)(?::(\$?[A-Z]{1,3}\$?[0-9]+))?`)
+
+func isOdsNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+// odsFormula translates the Excel formula to OpenFormula:
+// SUM(A1:A5,'Sheet 1'!$B$2) -> of:=SUM([.A1:.A5];[$'Sheet 1'.$B$2])
+func odsFormula(formula string) string {
+	var sb strings.Builder
+	sb.WriteString("of:=")
+	for i := 0; i < len(formula); {
+		c := formula[i]
+		if c == '"' {
+			// string literal, "" stands for a quote inside
+			j := i + 1
+			for j < len(formula) {
+				if formula[j] == '"' {
+					if j+1 < len(formula) && formula[j+1] == '"' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j < len(formula) {
+				j++
+			}
+			sb.WriteString(formula[i:j])
+			i = j
+			continue
+		}
+		if c == ',' {
+			sb.WriteByte(';')
+			i++
+			continue
+		}
+		if m := odsReferenceRe.FindStringSubmatchIndex(formula[i:]); m != nil && (i+m[1] == len(formula) ||
+			!isOdsNameChar(formula[i+m[1]]) && formula[i+m[1]] != '(') {
+			sb.WriteString("[" + odsAddress(formula[i:i+m[1]]) + "]")
+			i += m[1]
+			continue
+		}
+		if isOdsNameChar(c) {
+			// function or defined name
+			j := i
+			for j < len(formula) && isOdsNameChar(formula[j]) {
+				j++
+			}
+			sb.WriteString(strings.TrimPrefix(formula[i:j], "_xlfn."))
+			i = j
+			continue
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return sb.String()
+}
+
+// odsAddress translates the Excel reference to the OpenDocument one: 'Sheet 1'!$A$1:$B$2 -> $'Sheet 1'.$A$1:.$B$2
+func odsAddress(ref string) string {
+	sheet := ""
+	if idx := strings.LastIndex(ref, "!"); idx >= 0 {
+		sheet = ref[:idx]
+		if !strings.HasPrefix(sheet, "'") {
+			sheet = "'" + sheet + "'"
+		}
+		sheet = "$" + sheet
+		ref = ref[idx+1:]
+	}
+	parts := strings.Split(ref, ":")
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = sheet + "." + part
+		} else {
+			parts[i] = "." + part
+		}
+	}
+	return strings.Join(parts, ":")
+}
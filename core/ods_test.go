@@ -0,0 +1,78 @@
+package core
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOdsFormula(t *testing.T) {
+	for formula, expected := range map[string]string{
+		"SUM(A1:B2)":                                           "of:=SUM([.A1:.B2])",
+		"SUBTOTAL(9,$D$3:$D$9)*HoursPerUnit":                   "of:=SUBTOTAL(9;[.$D$3:.$D$9])*HoursPerUnit",
+		"'Sheet 1'!$A$1+'It''s'!B2:C3":                         "of:=[$'Sheet 1'.$A$1]+[$'It''s'.B2:.C3]",
+		`IF(F6="",1,INDEX(RiskFactors,MATCH(F6,RiskNames,0)))`: `of:=IF([.F6]="";1;INDEX(RiskFactors;MATCH([.F6];RiskNames;0)))`,
+		`_xlfn.SWITCH(G3,"a,b",1)`:                             `of:=SWITCH([.G3];"a,b";1)`,
+		`LOG10(A1)&"A1"`:                                       `of:=LOG10([.A1])&"A1"`,
+	} {
+		if actual := odsFormula(formula); actual != expected {
+			t.Fatalf("%s: expected %s, got %s", formula, expected, actual)
+		}
+	}
+}
+
+func TestGenerateOds(t *testing.T) {
+	project := mustNoError(t, projData)
+	fileName := filepath.Join(t.TempDir(), "report.ods")
+	if err := GenerateOds(project, fileName, ExcelOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.OpenReader(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.File[0].Name != "mimetype" || r.File[0].Method != zip.Store {
+		t.Fatalf("mimetype must go first uncompressed")
+	}
+	var content string
+	for _, f := range r.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bytes, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = string(bytes)
+	}
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("content must be well-formed: %v", err)
+		}
+	}
+	for _, expected := range []string{
+		`table:name="` + SheetParameters + `"`,
+		`<table:named-range table:name="` + nameRiskFactors + `"`,
+		`table:content-validation-name="val1"`,
+		`table:formula="of:=`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("must contain %s", expected)
+		}
+	}
+	if strings.Contains(content, "SWITCH") {
+		t.Fatalf("must not use SWITCH")
+	}
+}
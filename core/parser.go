@@ -2,7 +2,7 @@ package core
 
 import (
 	"fmt"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -10,9 +10,9 @@ import (
 // TODO validate mandatory directives present
 // TODO validate rate(s) absent for resources in tasks
 // TODO validate that derived resources are not referred in tasks
-// TODO how can we set custom team resources (w titles)
 
 type directiveVals struct {
+	pos    Pos
 	value  string
 	values map[string]string
 	directiveDef
@@ -22,14 +22,39 @@ type projParsed struct {
 	directives   map[string]directiveVals // each directive can go at most one time
 	team         []resourceRecord
 	tasksRecords []taskRecord
+	scenarios    []scenarioRecord
+	includes     []includeRecord
+	sections     map[string][]string // the items of the assumptions, exclusions and notes
+}
+
+// scenarioRecord holds overrides of a scenario on top of the base project
+type scenarioRecord struct {
+	pos        Pos
+	name       string
+	directives map[string]directiveVals
+	team       []resourceRecord
+	exclusions []taskSelector
+	tasks      []taskRecord // added, or replacing the base tasks of the same category and title
+}
+
+// taskSelector matches tasks by category along with the nested ones, and by title if set
+type taskSelector struct {
+	category string
+	title    string
+}
+
+func (ts taskSelector) matches(task taskRecord) bool {
+	return categoryMatches(ts.category, task.category) && (ts.title == "" || ts.title == task.title)
 }
 
 type resourceRecord struct {
+	pos           Pos
 	id            string
 	resourceProps map[string]string
 }
 
 type taskRecord struct {
+	pos       Pos
 	category  string
 	title     string
 	taskProps map[string]string
@@ -59,6 +84,96 @@ func (projParsed projParsed) getKVPairs(directive directiveDef) *map[string]stri
 	}
 }
 
+// withScenario returns a copy of the parsed project with the scenario overrides applied
+func (projParsed projParsed) withScenario(scenario scenarioRecord, errors *ProjectParseError) projParsed {
+	res := projParsed
+	res.scenarios = nil
+
+	res.directives = map[string]directiveVals{}
+	for k, v := range projParsed.directives {
+		res.directives[k] = v
+	}
+	for k, v := range scenario.directives {
+		if base, exists := res.directives[k]; exists && v.directiveType == DtKeyVal {
+			v.values = mergeProps(base.values, v.values)
+		} else if k == directiveRisks.name {
+			// override the default risks
+			standardRisks := map[string]string{}
+			for risk, factor := range StandardRisks() {
+				standardRisks[risk] = strconv.FormatFloat(factor, 'f', -1, 64)
+			}
+			v.values = mergeProps(standardRisks, v.values)
+		}
+		res.directives[k] = v
+	}
+
+	res.team = append([]resourceRecord{}, projParsed.team...)
+	for _, override := range scenario.team {
+		found := false
+		for i, r := range res.team {
+			if r.id == override.id {
+				res.team[i].resourceProps = mergeProps(r.resourceProps, override.resourceProps)
+				found = true
+			}
+		}
+		if !found {
+			res.team = append(res.team, override)
+		}
+	}
+
+	res.tasksRecords = []taskRecord{}
+	excluded := map[taskSelector]bool{}
+	for _, task := range projParsed.tasksRecords {
+		included := true
+		for _, selector := range scenario.exclusions {
+			if selector.matches(task) {
+				excluded[selector] = true
+				included = false
+			}
+		}
+		if included {
+			res.tasksRecords = append(res.tasksRecords, task)
+		}
+	}
+	for _, task := range scenario.tasks {
+		replaced := false
+		for i, existing := range res.tasksRecords {
+			if existing.category == task.category && existing.title == task.title {
+				res.tasksRecords[i] = task
+				replaced = true
+			}
+		}
+		if !replaced {
+			res.tasksRecords = append(res.tasksRecords, task)
+		}
+	}
+	errors.pos = scenario.pos
+	for _, selector := range scenario.exclusions {
+		if !excluded[selector] {
+			errors.addErrorf("No tasks to exclude: %s", selector)
+		}
+	}
+	return res
+}
+
+func (ts taskSelector) String() string {
+	if ts.title == "" {
+		return ts.category
+	}
+	return ts.category + " | " + ts.title
+}
+
+func mergeProps(base, overrides map[string]string) map[string]string {
+	res := map[string]string{}
+	for k, v := range base {
+		res[k] = v
+	}
+	for k, v := range overrides {
+		res[k] = v
+	}
+	return res
+}
+
 type directiveType int8
 
 const (
@@ -79,10 +194,21 @@ var (
 	directiveAcceptancePercent = newDirectiveDef("acceptance_percent", DtSingleValue)
 	directiveRisks             = newDirectiveDef("risks", DtKeyVal)
 	directiveDesiredDuration   = newDirectiveDef("desired_duration", DtSingleValue)
+	directiveRateCard          = newDirectiveDef("rate_card", DtSingleValue)
 )
 
 var directives = map[string]directiveDef{}
 
+// DirectiveNames lists the directives of the text format, sorted
+func DirectiveNames() []string {
+	var res []string
+	for name := range directives {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
 func newDirectiveDef(name string, directiveType directiveType) directiveDef {
 	d := directiveDef{
 		name:          name,
@@ -92,17 +218,39 @@ func newDirectiveDef(name string, directiveType directiveType) directiveDef {
 	return d
 }
 
-var spaceRe = regexp.MustCompile("[ \t]+")
+// ProjectError is a problem of the project along with its position, if known
+type ProjectError struct {
+	Pos     Pos
+	Message string
+}
+
+func (pe ProjectError) String() string {
+	switch {
+	case pe.Pos.File != "" && pe.Pos.Line == 0:
+		return fmt.Sprintf("%s: %s", pe.Pos.File, pe.Message)
+	case pe.Pos.File != "":
+		return fmt.Sprintf("%s:%d: %s", pe.Pos.File, pe.Pos.Line, pe.Message)
+	case pe.Pos.Line == 0:
+		return pe.Message
+	}
+	return fmt.Sprintf("line %d: %s", pe.Pos.Line, pe.Message)
+}
 
 type ProjectParseError struct {
-	errors []string
+	errors []ProjectError
+	pos    Pos // of the errors being added
+}
+
+// Errors lists all the problems found
+func (ppe *ProjectParseError) Errors() []ProjectError {
+	return ppe.errors
 }
 
 func (ppe *ProjectParseError) hasErrors() bool {
 	return len(ppe.errors) > 0
 }
 func (ppe *ProjectParseError) addError(error string) {
-	ppe.errors = append(ppe.errors, error)
+	ppe.errors = append(ppe.errors, ProjectError{Pos: ppe.pos, Message: error})
 }
 func (ppe *ProjectParseError) addErrorf(errorF string, args ...any) {
 	ppe.addError(fmt.Sprintf(errorF, args...))
@@ -115,14 +263,26 @@ func (ppe *ProjectParseError) intOrAddError(v string, errorF string, args ...any
 	return int(intVal)
 }
 func (ppe *ProjectParseError) floatOrAddErrorf(v string, errorF string, args ...any) float64 {
-	float, err := strconv.ParseFloat(v, 32)
+	float, err := strconv.ParseFloat(v, 64)
 	if err != nil {
 		ppe.addErrorf(errorF, args...)
 	}
 	return float
 }
 func (ppe *ProjectParseError) Error() string {
-	return strings.Join(ppe.errors, "\n")
+	var lines []string
+	for _, e := range ppe.errors {
+		lines = append(lines, e.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addPrefixed adds the errors of a part of the project, like a scenario
+func (ppe *ProjectParseError) addPrefixed(prefix string, other *ProjectParseError) {
+	for _, e := range other.errors {
+		e.Message = prefix + e.Message
+		ppe.errors = append(ppe.errors, e)
+	}
 }
 
 func (ppe *ProjectParseError) addOtherError(err error) {
@@ -130,20 +290,93 @@ func (ppe *ProjectParseError) addOtherError(err error) {
 		return
 	}
 	if parseError, ok := err.(*ProjectParseError); ok {
-		ppe.errors = append(ppe.errors, parseError.errors...)
+		ppe.addPrefixed("", parseError)
 	} else {
 		ppe.addError(err.Error())
 	}
 }
 
-const risksKey = "risks"
+const (
+	risksKey    = "risks"
+	scenarioKey = "scenario"
+	excludeKey  = "exclude"
+	includeKey  = "include"
+
+	// the task properties that are not efforts, also the first words of the indented lines following the task
+	noteKey       = "note"
+	assumptionKey = "assumption"
+	outOfScopeKey = "out_of_scope"
+)
 
-func ProjectFromString(projData string) (Project, error) {
-	proj := Project{}
-	errors := &ProjectParseError{}
+var taskTextKeys = map[string]bool{noteKey: true, assumptionKey: true, outOfScopeKey: true}
 
+// optionalKey marks the task left out of the totals unless the client includes it, `optional` alone is `optional=true`
+const optionalKey = "optional"
+
+// the sections of the project-wide text, every line of them is an item
+const (
+	assumptionsKey = "assumptions"
+	exclusionsKey  = "exclusions"
+	notesKey       = "notes"
+)
+
+var sectionKeys = []string{assumptionsKey, exclusionsKey, notesKey}
+
+func isSectionKey(word string) bool {
+	return containsString(sectionKeys, word)
+}
+
+// ProjectFromString parses the project text, it can't include other files. See ProjectFromFile for that.
+func ProjectFromString(projData string) (Project, error) {
 	projParsed, err := parseProj(projData)
+	return projectWithScenarios(projParsed, withFileOnlyErrors(projParsed, err))
+}
+
+// withFileOnlyErrors adds the errors of the includes and the rate card to the parse error
+// of the project that is not read from a file, the paths can't be resolved for it
+func withFileOnlyErrors(projParsed projParsed, err error) error {
+	rateCard, hasRateCard := projParsed.directives[directiveRateCard.name]
+	if len(projParsed.includes) == 0 && !hasRateCard {
+		return err
+	}
+	errors := &ProjectParseError{}
 	errors.addOtherError(err)
+	for _, include := range projParsed.includes {
+		errors.pos = include.pos
+		errors.addError("include is only possible in the project read from a file")
+	}
+	if hasRateCard {
+		errors.pos = rateCard.pos
+		errors.addError("rate_card is only possible in the project read from a file")
+	}
+	return errors
+}
+
+// projectWithScenarios builds and validates the project parsed from any of the supported formats
+func projectWithScenarios(projParsed projParsed, parseErr error) (Project, error) {
+	errors := &ProjectParseError{}
+	errors.addOtherError(parseErr)
+
+	proj := projectFromParsed(projParsed, errors)
+
+	for _, scenario := range projParsed.scenarios {
+		scenarioErrors := &ProjectParseError{}
+		scenarioProj := projectFromParsed(projParsed.withScenario(scenario, scenarioErrors), scenarioErrors)
+		errors.addPrefixed("Scenario "+scenario.name+": ", scenarioErrors)
+		proj.Scenarios = append(proj.Scenarios, Scenario{Name: scenario.name, Project: scenarioProj})
+	}
+
+	if !errors.hasErrors() {
+		return proj, nil
+	}
+	return proj, errors
+}
+
+func projectFromParsed(projParsed projParsed, errors *ProjectParseError) Project {
+	proj := Project{directivesPos: map[string]Pos{}}
+	for name, v := range projParsed.directives {
+		proj.directivesPos[name] = v.pos
+	}
 
 	{
 		name := projParsed.getSingleVal(directiveProject)
@@ -160,6 +393,7 @@ func ProjectFromString(projData string) (Project, error) {
 
 	{
 		timeUnit := projParsed.getSingleVal(directiveTimeUnit)
+		errors.pos = proj.directivesPos[directiveTimeUnit.name]
 		if timeUnit != nil {
 			proj.TimeUnit = TimeUnitFromString(*timeUnit)
 			if proj.TimeUnit == TimeUnitUnknown {
@@ -170,6 +404,7 @@ func ProjectFromString(projData string) (Project, error) {
 
 	{
 		currency := projParsed.getSingleVal(directiveCurrency)
+		errors.pos = proj.directivesPos[directiveCurrency.name]
 		if currency != nil {
 			proj.Currency = CurrencyFromString(*currency)
 			if proj.Currency == CurrencyUnknown {
@@ -180,23 +415,22 @@ func ProjectFromString(projData string) (Project, error) {
 
 	{
 		acceptancePercent := projParsed.getSingleVal(directiveAcceptancePercent)
+		errors.pos = proj.directivesPos[directiveAcceptancePercent.name]
 		if acceptancePercent != nil {
-			float, err := strconv.ParseFloat(*acceptancePercent, 32)
-			if err != nil || float < 0 || float > 100 {
-				errors.addError("Wrong acceptance_percent: " + *acceptancePercent)
-			}
-			proj.AcceptancePercent = float
+			proj.AcceptancePercent = errors.floatOrAddErrorf(*acceptancePercent, "Wrong acceptance_percent: %s", *acceptancePercent)
 		}
 	}
 
 	{
 		risks := projParsed.getKVPairs(directiveRisks)
+		errors.pos = proj.directivesPos[directiveRisks.name]
 		if risks != nil {
 			proj.Risks = map[string]float64{}
 			for k, v := range *risks {
-				float, err := strconv.ParseFloat(v, 32)
-				if err != nil || float < 1 {
+				float, err := strconv.ParseFloat(v, 64)
+				if err != nil {
 					errors.addErrorf("Wrong risk value for %s: %s", k, v)
+					float = 1 // so that it's not reported once again by validation
 				}
 				proj.Risks[k] = float
 			}
@@ -207,66 +441,86 @@ func ProjectFromString(projData string) (Project, error) {
 	}
 
 	for _, r := range projParsed.team {
+		errors.pos = r.pos
 		title := r.resourceProps["title"]
 		resourceId := r.id
 		if title == "" {
-			title = standardResourceTypes[resourceId]
+			title = defaultTitle(resourceId)
 		}
 		var cnt int
 		if cntStr, exists := r.resourceProps["cnt"]; exists {
 			cnt = errors.intOrAddError(cntStr, "Wrong team count value for %s: %s", resourceId, cntStr)
-			if cnt < 0 {
-				errors.addError(fmt.Sprintf("Team count must be >= 0 for %s: %s", resourceId, cntStr))
-			}
 		}
 		var rate float64
-		if rateStr, exists := r.resourceProps["rate"]; exists {
+		rateStr, hasRate := r.resourceProps["rate"]
+		if hasRate {
 			rate = errors.floatOrAddErrorf(rateStr, "Wrong rate value for %s: %s", resourceId, rateStr)
-			if rate < 0 {
-				errors.addError(fmt.Sprintf("Rate must be >= 0 for %s: %s", resourceId, rateStr))
+		}
+		formula, hasFormula := r.resourceProps["formula"]
+		if role, registered := registeredRole(resourceId); registered {
+			if !hasRate {
+				rate = role.Rate
+			}
+			if !hasFormula {
+				formula = role.Formula
+			}
+		}
+		velocity := 1.0
+		if velocityStr, exists := r.resourceProps["velocity"]; exists {
+			velocity = errors.floatOrAddErrorf(velocityStr, "Wrong velocity value for %s: %s", resourceId, velocityStr)
+			if velocity <= 0 {
+				// 0 stands for not set in Resource
+				errors.addErrorf("Velocity must be > 0 for %s: %s", resourceId, velocityStr)
 			}
 		}
 		proj.Team = append(proj.Team, Resource{
-			Id:      resourceId,
-			Title:   title,
-			Rate:    rate,
-			Count:   cnt,
-			Formula: r.resourceProps["formula"],
+			Pos:      r.pos,
+			Id:       resourceId,
+			Title:    title,
+			Rate:     rate,
+			Count:    cnt,
+			Velocity: velocity,
+			Formula:  formula,
+
+			rateInherited: !hasRate,
+			rateGiven:     hasRate,
 		})
 	}
 
 	for _, taskRecord := range projParsed.tasksRecords {
+		errors.pos = taskRecord.pos
 		risk := taskRecord.taskProps[risksKey]
-		if risk != "" {
-			if _, exists := proj.Risks[risk]; !exists {
-				errors.addError("Wrong risks name: " + risk)
-			}
-		}
 		efforts := map[string]float64{}
 		for k, v := range taskRecord.taskProps {
-			if k != risksKey {
-				effort := errors.floatOrAddErrorf(v, "Wrong effort for task %s|%s for resource %s: %s", taskRecord.category, taskRecord.title, k, v)
-				if effort < 0 {
-					errors.addErrorf("Effort should be >= 0 for task %s|%s for resource %s: %s", taskRecord.category, taskRecord.title, k, v)
-				}
-				efforts[k] = effort
+			if k != risksKey && k != tagsKey && k != optionalKey && !taskTextKeys[k] {
+				efforts[k] = errors.floatOrAddErrorf(v, "Wrong effort for task %s|%s for resource %s: %s", taskRecord.category, taskRecord.title, k, v)
 			}
 		}
-		for k := range efforts {
-			if proj.ResourceById(k) == nil {
-				errors.addError("Wrong resource name in efforts: " + k)
+		optional := false
+		if optionalStr, exists := taskRecord.taskProps[optionalKey]; exists {
+			var err error
+			if optional, err = strconv.ParseBool(optionalStr); err != nil {
+				errors.addErrorf("Wrong optional value for task %s|%s: %s", taskRecord.category, taskRecord.title, optionalStr)
 			}
 		}
 		proj.Tasks = append(proj.Tasks, Task{
+			Pos:      taskRecord.pos,
 			Category: taskRecord.category,
 			Title:    taskRecord.title,
 			Risk:     risk,
 			Work:     efforts,
+			Tags:     ParseTags(taskRecord.taskProps[tagsKey]),
+			Optional: optional,
+
+			Notes:       taskRecord.taskProps[noteKey],
+			Assumptions: taskRecord.taskProps[assumptionKey],
+			OutOfScope:  taskRecord.taskProps[outOfScopeKey],
 		})
 	}
 
 	{
 		desiredDurationStr := projParsed.getSingleVal(directiveDesiredDuration)
+		errors.pos = proj.directivesPos[directiveDesiredDuration.name]
 		if desiredDurationStr != nil {
 			duration, err := ParseDuration(*desiredDurationStr)
 			if err != nil {
@@ -276,10 +530,14 @@ func ProjectFromString(projData string) (Project, error) {
 		}
 	}
 
-	if !errors.hasErrors() {
-		return proj, nil
-	}
-	return proj, errors
+	proj.Assumptions = projParsed.sections[assumptionsKey]
+	proj.Exclusions = projParsed.sections[exclusionsKey]
+	proj.Notes = projParsed.sections[notesKey]
+
+	validateProject(proj, errors)
+	errors.pos = Pos{}
+
+	return proj
 }
 
 type parseMode int
@@ -288,16 +546,19 @@ const (
 	pmDirectives parseMode = iota
 	pmTeam
 	pmTasks
+	pmScenario
+	pmSection
 )
 
-func parseKeyValPairs(str string, errors *ProjectParseError) map[string]string {
-	str = strings.TrimSpace(str)
+// parseKeyValPairs parses the words like `key=value` and `key2="value with spaces"`
+func parseKeyValPairs(tokens []token, errors *ProjectParseError) map[string]string {
 	values := map[string]string{}
-	valParts := spaceRe.Split(str, -1)
-	for _, valPart := range valParts {
-		keyVal := strings.SplitN(valPart, "=", 2)
-		if len(keyVal) < 2 {
-			errors.addErrorf("wrong key=value: %s", valPart)
+	for _, t := range tokens {
+		keyVal := strings.SplitN(t.text, "=", 2)
+		if t.kind == tokPipe {
+			errors.addError("unexpected |, escape it as \\|")
+		} else if len(keyVal) < 2 {
+			errors.addErrorf("wrong key=value: %s", t.raw)
 		} else {
 			values[keyVal[0]] = keyVal[1]
 		}
@@ -305,61 +566,208 @@ func parseKeyValPairs(str string, errors *ProjectParseError) map[string]string {
 	return values
 }
 
+// parseTaskProps parses the efforts and other properties of the task along with the `optional` marker
+func parseTaskProps(tokens []token, errors *ProjectParseError) map[string]string {
+	var keyVals []token
+	optional := false
+	for _, t := range tokens {
+		if t.kind == tokWord && t.text == optionalKey {
+			optional = true
+		} else {
+			keyVals = append(keyVals, t)
+		}
+	}
+	res := parseKeyValPairs(keyVals, errors)
+	if optional {
+		res[optionalKey] = "true"
+	}
+	return res
+}
+
+func parseDirective(tokens []token, pos Pos, directiveValues map[string]directiveVals, errors *ProjectParseError) {
+	directive, found := directives[tokens[0].text]
+	if !found {
+		errors.addError("Unknown directive: " + tokens[0].text)
+		return
+	}
+	if len(tokens) < 2 {
+		errors.addError("Directive without value: " + directive.name)
+		return
+	}
+	if _, exists := directiveValues[directive.name]; exists {
+		errors.addError("Duplicating directive: " + directive.name)
+	} else if directive.directiveType == DtSingleValue {
+		directiveValues[directive.name] = directiveVals{directiveDef: directive, pos: pos, value: joinText(tokens[1:])}
+	} else if directive.directiveType == DtKeyVal {
+		directiveValues[directive.name] = directiveVals{directiveDef: directive, pos: pos, values: parseKeyValPairs(tokens[1:], errors)}
+	}
+}
+
+// isContinuation tells if the line of the tasks section adds the notes to the task above it
+func isContinuation(text string, line lexedLine) bool {
+	switch line.first() {
+	case "team", "tasks", scenarioKey, includeKey:
+		return false
+	}
+	return (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && !line.hasPipe()
+}
+
+// sectionItem is the text of the line of the assumptions, exclusions or notes, the leading "-" of a list is dropped
+func sectionItem(tokens []token) string {
+	if len(tokens) > 1 && tokens[0].raw == "-" {
+		tokens = tokens[1:]
+	}
+	return joinText(tokens)
+}
+
+func joinLines(text, line string) string {
+	if text == "" {
+		return line
+	}
+	return text + "\n" + line
+}
+
 func parseProj(projData string) (projParsed, error) {
+	return parseProjFile(projData, "")
+}
+
+// parseProjFile parses the text of the file, the includes are recorded but not read
+func parseProjFile(projData string, file string) (projParsed, error) {
 	errors := &ProjectParseError{}
 	projParsed := projParsed{
 		directives:   map[string]directiveVals{},
 		tasksRecords: []taskRecord{},
+		sections:     map[string][]string{},
 	}
 	lines := strings.Split(projData, "\n")
 	mode := pmDirectives
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.Index(line, "#") == 0 {
+	section := ""  // the key of the section in pmSection
+	lastTask := -1 // the task the indented lines that follow add the notes to
+	for i, text := range lines {
+		pos := Pos{File: file, Line: i + 1}
+		errors.pos = pos
+		line, err := lexLine(text)
+		if err != nil {
+			errors.addError(err.Error())
+			continue
+		}
+		tokens := line.tokens
+		if len(tokens) == 0 {
 			continue
-		} else if line == "tasks" {
+		} else if mode == pmTasks && isContinuation(text, line) {
+			if len(projParsed.tasksRecords) == 0 || lastTask != len(projParsed.tasksRecords)-1 {
+				errors.addError("the indented line must follow the task")
+				continue
+			}
+			task := &projParsed.tasksRecords[lastTask]
+			key, value := noteKey, joinText(tokens)
+			if taskTextKeys[tokens[0].text] {
+				key, value = tokens[0].text, joinText(tokens[1:])
+			}
+			task.taskProps[key] = joinLines(task.taskProps[key], value)
+			continue
+		}
+		lastTask = -1
+		if len(tokens) == 1 && tokens[0].raw == "tasks" {
 			mode = pmTasks
 			continue
-		} else if line == "team" {
+		} else if len(tokens) == 1 && tokens[0].raw == "team" {
 			mode = pmTeam
 			continue
+		} else if len(tokens) == 1 && isSectionKey(tokens[0].raw) {
+			mode, section = pmSection, tokens[0].raw
+			continue
+		}
+		if line.first() == includeKey && !line.hasPipe() {
+			if mode == pmScenario {
+				errors.addError("include is not possible in a scenario")
+			} else if include, ok := parseInclude(tokens, errors); ok {
+				include.pos = pos
+				include.team = len(projParsed.team)
+				include.tasks = len(projParsed.tasksRecords)
+				projParsed.includes = append(projParsed.includes, include)
+			}
+			continue
+		}
+		if line.first() == scenarioKey && !line.hasPipe() {
+			mode = pmScenario
+			if len(tokens) < 2 {
+				errors.addError("scenario should have a name")
+			}
+			name := joinText(tokens[1:])
+			for _, scenario := range projParsed.scenarios {
+				if scenario.name == name {
+					errors.addError("Duplicating scenario: " + name)
+				}
+			}
+			projParsed.scenarios = append(projParsed.scenarios, scenarioRecord{
+				pos:        pos,
+				name:       name,
+				directives: map[string]directiveVals{},
+			})
+			continue
 		}
-		parts := spaceRe.Split(line, 2)
 		if mode == pmDirectives {
-			if directive, found := directives[parts[0]]; found {
-				if _, exists := projParsed.directives[directive.name]; exists {
-					errors.addError("Duplicating directive: " + directive.name)
-				} else if directive.directiveType == DtSingleValue {
-					projParsed.directives[directive.name] = directiveVals{directiveDef: directive, value: strings.TrimSpace(parts[1])}
-				} else if directive.directiveType == DtKeyVal {
-					projParsed.directives[directive.name] = directiveVals{directiveDef: directive, values: parseKeyValPairs(parts[1], errors)}
+			parseDirective(tokens, pos, projParsed.directives, errors)
+		} else if mode == pmScenario {
+			scenario := &projParsed.scenarios[len(projParsed.scenarios)-1]
+			if line.first() == "team" && len(tokens) > 1 {
+				scenario.team = append(scenario.team, resourceRecord{
+					pos:           pos,
+					id:            tokens[1].text,
+					resourceProps: parseKeyValPairs(tokens[2:], errors),
+				})
+			} else if line.first() == excludeKey && len(tokens) > 1 {
+				cells := lexedLine{tokens: tokens[1:]}.cells()
+				selector := taskSelector{category: joinText(cells[0])}
+				if len(cells) > 1 {
+					selector.title = joinText(cells[1])
+				}
+				if len(cells) > 2 {
+					errors.addError("exclude should have format: cat | title")
 				}
+				scenario.exclusions = append(scenario.exclusions, selector)
+			} else if line.hasPipe() {
+				cells := line.cells()
+				if len(cells) != 3 {
+					errors.addErrorf("task should have format: cat | title | efforts")
+					continue
+				}
+				scenario.tasks = append(scenario.tasks, taskRecord{
+					pos:       pos,
+					category:  joinText(cells[0]),
+					title:     joinText(cells[1]),
+					taskProps: parseTaskProps(cells[2], errors),
+				})
 			} else {
-				errors.addError("Unknown directive: " + parts[0])
+				parseDirective(tokens, pos, scenario.directives, errors)
 			}
 		} else if mode == pmTasks {
-			taskParts := strings.Split(line, "|")
-			if len(taskParts) != 3 {
+			cells := line.cells()
+			if len(cells) != 3 {
 				errors.addErrorf("task should have format: cat | title | efforts")
+				continue
 			}
 			projParsed.tasksRecords = append(projParsed.tasksRecords, taskRecord{
-				category:  strings.TrimSpace(taskParts[0]),
-				title:     strings.TrimSpace(taskParts[1]),
-				taskProps: parseKeyValPairs(taskParts[2], errors),
+				pos:       pos,
+				category:  joinText(cells[0]),
+				title:     joinText(cells[1]),
+				taskProps: parseTaskProps(cells[2], errors),
 			})
+			lastTask = len(projParsed.tasksRecords) - 1
+		} else if mode == pmSection {
+			projParsed.sections[section] = append(projParsed.sections[section], sectionItem(tokens))
 		} else if mode == pmTeam {
-			keyValPairs := map[string]string{}
-			if len(parts) > 1 {
-				keyValPairs = parseKeyValPairs(parts[1], errors)
-			}
 			projParsed.team = append(projParsed.team, resourceRecord{
-				id:            parts[0],
-				resourceProps: keyValPairs,
+				pos:           pos,
+				id:            tokens[0].text,
+				resourceProps: parseKeyValPairs(tokens[1:], errors),
 			})
 		} else {
 			panic("Unknown mode")
 		}
 	}
+	errors.pos = Pos{}
 	if !errors.hasErrors() {
 		return projParsed, nil
 	}
@@ -1,7 +1,9 @@
 package core
 
 import (
+	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -75,6 +77,15 @@ func mustNoError(t *testing.T, s string) Project {
 	return project
 }
 
+func TestNumbersPrecision(t *testing.T) {
+	project := mustNoError(t, "acceptance_percent 7.3\nrisks low=1.1\nteam\nbe cnt=1 rate=40.7\ntasks\nA | B | be=0.1\n")
+	if project.Risks["low"] != 1.1 || project.AcceptancePercent != 7.3 || project.Tasks[0].Work["be"] != 0.1 || project.Team[0].Rate != 40.7 {
+		t.Fatalf("the numbers must be as written: %v %v %v", project.Risks, project.AcceptancePercent, project.Tasks[0].Work)
+	}
+	if d, err := ParseDuration("1.1 day"); err != nil || d.ToHours() != 1.1*8 {
+		t.Fatalf("wrong duration: %+v %v", d, err)
+	}
+}
 func TestWrongCurrency(t *testing.T) {
 	mustBeError(t, `currency wrong`)
 }
@@ -188,3 +199,350 @@ a|b|be=35 fe=2 risks=low
 //func TestParsing3(t *testing.T) {
 //	GenerateExcel(ProjectFromString(projData), "../Book3.xlsx")
 //}
+
+func TestSeniorityLevels(t *testing.T) {
+	project := mustNoError(t, `
+team
+be.senior cnt=1 rate=60 velocity=1.5
+be.junior cnt=2 rate=30 velocity=0.75
+qa formula=be*0.3
+tasks
+a|b|be=10
+`)
+	if len(project.WorkRoles()) != 1 || project.WorkRoles()[0].Id != "be" {
+		t.Fatalf("be must be the only work role")
+	}
+	if project.TeamAsMap()["be.senior"].Title != "Back dev (senior)" {
+		t.Fatalf("wrong title")
+	}
+}
+func TestSeniorityLevelsMixedWithRole(t *testing.T) {
+	mustBeError(t, `
+team
+be
+be.senior cnt=1
+`)
+}
+func TestSeniorityLevelsWithoutCount(t *testing.T) {
+	mustBeError(t, `
+team
+be.senior
+be.junior
+`)
+}
+func TestSeniorityLevelInEfforts(t *testing.T) {
+	_, err := ProjectFromString(`
+team
+be.senior cnt=1 rate=60
+tasks
+API | Login | be.senior=10
+`)
+	if err == nil || err.Error() != "line 5: Effort for be.senior must be given for the role be" {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
+func TestSeniorityLevelsErrorsInTeamOrder(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		_, err := ProjectFromString(`
+team
+qa.senior
+fe.senior
+be.senior
+do.senior
+`)
+		if err == nil || err.Error() != "line 3: At least one seniority level of qa must have cnt > 0\n"+
+			"line 4: At least one seniority level of fe must have cnt > 0\n"+
+			"line 5: At least one seniority level of be must have cnt > 0\n"+
+			"line 6: At least one seniority level of do must have cnt > 0" {
+			t.Fatalf("the errors must follow the team: %v", err)
+		}
+	}
+}
+func TestWrongVelocity(t *testing.T) {
+	mustBeError(t, `
+team
+be cnt=1 velocity=0
+`)
+}
+func TestWrongFormula(t *testing.T) {
+	mustBeError(t, `
+team
+be cnt=1
+qa formula=(zz+be)*0.3
+`)
+}
+
+func TestScenarios(t *testing.T) {
+	project := mustNoError(t, `
+acceptance_percent 10
+team
+be cnt=2 rate=40
+tasks
+a|b|be=1 risks=high
+a|c|be=1
+d|e|be=1
+
+scenario lean
+acceptance_percent 5
+risks high=3
+team be cnt=1
+exclude a | c
+
+scenario mvp
+exclude d
+`)
+	if len(project.Scenarios) != 2 {
+		t.Fatalf("must be 2 scenarios")
+	}
+	lean := project.Scenarios[0].Project
+	if lean.AcceptancePercent != 5 || lean.Risks["high"] != 3 || lean.Risks["low"] == 0 {
+		t.Fatalf("lean directives must be overridden")
+	}
+	if be := lean.TeamAsMap()["be"]; be.Count != 1 || be.Rate != 40 {
+		t.Fatalf("lean team must be overridden")
+	}
+	if len(lean.Tasks) != 2 || len(project.Scenarios[1].Project.Tasks) != 2 {
+		t.Fatalf("tasks must be excluded")
+	}
+	if project.AcceptancePercent != 10 || project.TeamAsMap()["be"].Count != 2 || len(project.Tasks) != 3 {
+		t.Fatalf("base project must stay intact")
+	}
+}
+func TestScenarioTasks(t *testing.T) {
+	proj := `
+team
+be cnt=1 rate=40
+tasks
+API | Login | be=2
+API | Admin | be=5
+
+scenario full
+API   | Export | be=3 risks=high
+Admin | Audit  | be=1
+
+scenario rework
+exclude API | Admin
+API | Login | be=4
+`
+	project := mustNoError(t, proj)
+	full, rework := project.Scenarios[0].Project, project.Scenarios[1].Project
+	if len(full.Tasks) != 4 || full.Tasks[2].Title != "Export" || full.Tasks[2].Risk != "high" || full.Tasks[3].Category != "Admin" {
+		t.Fatalf("the scenario must add the tasks: %+v", full.Tasks)
+	}
+	if len(rework.Tasks) != 1 || rework.Tasks[0].Work["be"] != 4 || project.Tasks[0].Work["be"] != 2 {
+		t.Fatalf("the scenario must replace the task: %+v", rework.Tasks)
+	}
+	formatted, err := FormatProject(proj)
+	if err != nil || !strings.Contains(formatted, "scenario full\nAPI | Export | be=3 risks=high\n") {
+		t.Fatalf("wrong format: %s %v", formatted, err)
+	}
+	mustBeError(t, "team\nbe cnt=1\nscenario a\nAPI | Login\n")
+	mustBeError(t, "team\nbe cnt=1\nscenario a\nAPI | Login | zz=1\n")
+}
+func TestScenarioWrongExclusion(t *testing.T) {
+	mustBeError(t, `
+tasks
+a|b|
+scenario lean
+exclude zz
+`)
+}
+func TestScenarioWrongDirective(t *testing.T) {
+	mustBeError(t, `
+scenario lean
+currency wrong
+`)
+}
+
+func TestIncompleteTask(t *testing.T) {
+	mustBeError(t, `
+team
+be cnt=1 rate=40
+tasks
+API | Login
+`)
+}
+
+func TestQuotedValues(t *testing.T) {
+	project := mustNoError(t, `
+team
+bc   cnt=1 rate=80 title="Blockchain  Engineer"
+qa cnt=1 rate=20 formula="bc * 0.3"
+tasks
+Chain | Contracts | bc=5
+`)
+	if project.Team[0].Title != "Blockchain  Engineer" || project.Team[1].Formula != "bc * 0.3" {
+		t.Fatalf("wrong quoted values: %v", project.Team)
+	}
+	mustBeError(t, "team\nbc cnt=1 title=\"Blockchain Engineer\n")
+	formatted, err := FormatProject("team\nbc   cnt=1  title=\"Blockchain  Engineer\"\n")
+	if err != nil || formatted != "team\nbc cnt=1 title=\"Blockchain  Engineer\"\n" {
+		t.Fatalf("the quoted value must be kept: %q %v", formatted, err)
+	}
+}
+
+func TestHashAfterSpace(t *testing.T) {
+	// # after a space starts the comment even in the text, quoted or escaped it's kept
+	for text, expected := range map[string]string{
+		"project Issue #42":     "Issue",
+		`project "Issue #42"`:   "Issue #42",
+		`project Issue \#42`:    "Issue #42",
+		"project Issue#42":      "Issue#42",
+		"project Issue # 42 #1": "Issue",
+	} {
+		project := mustNoError(t, text+"\nteam\nbe cnt=1\ntasks\nAPI | \"Fix #1\" | be=1 #1\n")
+		if project.Name != expected {
+			t.Fatalf("%s: wrong name %q", text, project.Name)
+		}
+		if project.Tasks[0].Title != "Fix #1" {
+			t.Fatalf("wrong quoted title: %q", project.Tasks[0].Title)
+		}
+	}
+	// the rest of the task row is the comment then
+	mustBeError(t, "team\nbe cnt=1\ntasks\nAPI | Fix #1 | be=1\n")
+}
+
+func TestLineSyntax(t *testing.T) {
+	project := mustNoError(t, `
+project Shop # the name is "Shop"
+risks low=1.1 high=2 # no medium
+team
+be cnt=2 rate=40 title="Back \\ \"core\" dev" # two of them
+qa cnt=1 rate=20 formula="(be + be) * 0.1"
+tasks
+API | Login \| logout | be=3 risks=high # OAuth
+"C# | .NET" | Port | be=1
+`)
+	if project.Name != "Shop" || len(project.Risks) != 2 {
+		t.Fatalf("the comments must be skipped: %+v", project)
+	}
+	if project.Team[0].Title != `Back \ "core" dev` || project.Team[1].Formula != "(be + be) * 0.1" {
+		t.Fatalf("wrong quoted values: %v", project.Team)
+	}
+	if project.Tasks[0].Title != "Login | logout" || project.Tasks[1].Category != "C# | .NET" {
+		t.Fatalf("wrong escaped pipe: %v", project.Tasks)
+	}
+	mustBeError(t, "project Shop \\")
+	mustBeError(t, "team\nbe cnt=1 | rate=2\n")
+
+	formatted, err := FormatProject("team\nbe   cnt=1  title=\"Back  dev\"   # comment\ntasks\nA\\|B | C |  be=1 #x\n")
+	if err != nil || formatted != "team\nbe cnt=1 title=\"Back  dev\" # comment\ntasks\nA\\|B | C | be=1 #x\n" {
+		t.Fatalf("the quotes, escapes and comments must be kept: %q %v", formatted, err)
+	}
+}
+
+func TestFormatProject(t *testing.T) {
+	formatted, err := FormatProject(`
+
+project   My  shop
+risks low=1.1    high=2
+# the team
+team
+be   cnt=2 rate=40
+qa cnt=1    rate=20 formula=be*0.3
+
+
+tasks
+API	|Login 		| be=3   risks=high
+Backend API |  Orders | be=10
+scenario  lean
+exclude   API|Login
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `project My  shop
+risks low=1.1 high=2
+# the team
+team
+be cnt=2 rate=40
+qa cnt=1 rate=20 formula=be*0.3
+
+tasks
+API         | Login  | be=3 risks=high
+Backend API | Orders | be=10
+scenario lean
+exclude API | Login
+`
+	if formatted != expected {
+		t.Fatalf("wrong format:\n%s", formatted)
+	}
+	again, _ := FormatProject(formatted)
+	if again != formatted {
+		t.Fatalf("must be stable:\n%s", again)
+	}
+	if _, err := FormatProject("tasks\nAPI | Login\n"); err == nil {
+		t.Fatalf("must not format what doesn't parse")
+	}
+}
+
+func TestFormatKeepsProject(t *testing.T) {
+	for _, proj := range []string{projWithSections, projWithNotes, `
+team
+be cnt=1 rate=40
+tasks
+API | Login | be=3
+    exclusions
+    tasks
+API | Logout | be=2
+assumptions
+- the client provides the designs
+`} {
+		formatted, err := FormatProject(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, after := mustNoError(t, proj), mustNoError(t, formatted)
+		beforeYaml, _ := ProjectToYaml(before)
+		afterYaml, _ := ProjectToYaml(after)
+		if beforeYaml != afterYaml || len(before.Tasks) != len(after.Tasks) ||
+			strings.Join(before.Assumptions, ",") != strings.Join(after.Assumptions, ",") {
+			t.Fatalf("the formatting must keep the project:\n%s\n%s", proj, formatted)
+		}
+	}
+}
+
+const projWithSections = `
+project Shop
+author sales@example.com
+currency usd
+team
+be cnt=1 rate=40
+tasks
+API | Login | be=3
+assumptions
+- the client provides the designs
+- staging is hosted by the client # not by us
+exclusions
+the support after the launch
+notes
+- 50% upfront, 50% on the delivery
+`
+
+func TestProjectSections(t *testing.T) {
+	project := mustNoError(t, projWithSections)
+	if len(project.Assumptions) != 2 || project.Assumptions[1] != "staging is hosted by the client" ||
+		len(project.Exclusions) != 1 || project.Exclusions[0] != "the support after the launch" ||
+		len(project.Notes) != 1 || project.Notes[0] != "50% upfront, 50% on the delivery" {
+		t.Fatalf("wrong sections: %q %q %q", project.Assumptions, project.Exclusions, project.Notes)
+	}
+	if sections := project.Sections(); len(sections) != 3 || sections[1].Title != "Not included" {
+		t.Fatalf("wrong sections: %+v", sections)
+	}
+	if len(project.Tasks) != 1 {
+		t.Fatalf("the sections must end the tasks: %v", project.Tasks)
+	}
+
+	formatted, err := FormatProject(projWithSections)
+	if err != nil || !strings.Contains(formatted, "tasks\nAPI | Login | be=3\nassumptions\n- the client provides the designs\n- staging is hosted by the client # not by us\nexclusions\n") {
+		t.Fatalf("the sections must be kept: %s %v", formatted, err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSections(&buf, project); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "Assumptions:\n  1. the client provides the designs\n  2. staging") {
+		t.Fatalf("wrong sections:\n%s", buf.String())
+	}
+}
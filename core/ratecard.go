@@ -0,0 +1,180 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RateCard is the rates of the roles the organization uses in all its projects, see rates.yml.
+// The team members without rate take it from the card, and the title if they have none.
+type RateCard struct {
+	Name       string
+	Version    string
+	ValidFrom  time.Time // zero if not limited
+	ValidUntil time.Time // zero if not limited, the card is valid on this day
+	Roles      map[string]RateCardRole
+}
+
+// RateCardRole is a role like "be" or a role with seniority level like "be.senior"
+type RateCardRole struct {
+	Title string
+	Rates map[Currency]float64
+}
+
+const rateCardDateLayout = "2006-01-02"
+
+type rateCardYaml struct {
+	Name       string `yaml:"name"`
+	Version    string `yaml:"version"`
+	ValidFrom  string `yaml:"valid_from"`
+	ValidUntil string `yaml:"valid_until"`
+	Roles      map[string]struct {
+		Title string             `yaml:"title"`
+		Rates map[string]float64 `yaml:"rates"`
+	} `yaml:"roles"`
+}
+
+// RateCardFromYaml parses the rate card in the form of rates.yml
+func RateCardFromYaml(data string) (RateCard, error) {
+	var parsed rateCardYaml
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&parsed); err != nil {
+		return RateCard{}, fmt.Errorf("wrong rate card: %w", err)
+	}
+	card := RateCard{Name: parsed.Name, Version: parsed.Version, Roles: map[string]RateCardRole{}}
+	for _, date := range []struct {
+		str   string
+		value *time.Time
+	}{{parsed.ValidFrom, &card.ValidFrom}, {parsed.ValidUntil, &card.ValidUntil}} {
+		if date.str == "" {
+			continue
+		}
+		var err error
+		if *date.value, err = time.Parse(rateCardDateLayout, date.str); err != nil {
+			return RateCard{}, fmt.Errorf("wrong rate card date, must be like 2024-12-31: %s", date.str)
+		}
+	}
+	for id, role := range parsed.Roles {
+		rates := map[Currency]float64{}
+		for currencyStr, rate := range role.Rates {
+			currency := CurrencyFromString(currencyStr)
+			if currency == CurrencyUnknown {
+				return RateCard{}, fmt.Errorf("unknown currency of the rate card role %s: %s", id, currencyStr)
+			}
+			if rate < 0 {
+				return RateCard{}, fmt.Errorf("rate must be >= 0 for the rate card role %s: %v", id, rate)
+			}
+			rates[currency] = rate
+		}
+		card.Roles[id] = RateCardRole{Title: role.Title, Rates: rates}
+	}
+	return card, nil
+}
+
+// RateCardFromFile reads the rate card, its name is the name of the file if it doesn't have one
+func RateCardFromFile(path string) (RateCard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RateCard{}, err
+	}
+	return rateCardNamed(path, string(data))
+}
+
+func rateCardNamed(path, data string) (RateCard, error) {
+	card, err := RateCardFromYaml(data)
+	if err != nil {
+		return card, fmt.Errorf("%s: %w", path, err)
+	}
+	if card.Name == "" {
+		card.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return card, nil
+}
+
+// String is how the card is referred to in the reports, like "rates v2024.2"
+func (rc RateCard) String() string {
+	if rc.Version == "" {
+		return rc.Name
+	}
+	return rc.Name + " v" + rc.Version
+}
+
+// role finds the rate card role of the team member, falling back to the role without the seniority level
+func (rc RateCard) role(r Resource) (RateCardRole, bool) {
+	if role, exists := rc.Roles[r.Id]; exists {
+		return role, true
+	}
+	role, exists := rc.Roles[r.Role()]
+	if exists && role.Title != "" && r.Level() != "" {
+		role.Title += " (" + r.Level() + ")"
+	}
+	return role, exists
+}
+
+// WithRateCard takes the rates and titles the team members don't have from the card, the ones taken
+// from another card or the registered roles are replaced. The card must be valid on the date and have the rates in the currency
+// of the project. The scenarios take the rates from the card as well.
+func (p Project) WithRateCard(card RateCard, date time.Time) (Project, error) {
+	errors := &ProjectParseError{}
+	errors.pos = p.directivesPos[directiveRateCard.name]
+	day := date.Format(rateCardDateLayout)
+	if !card.ValidFrom.IsZero() && day < card.ValidFrom.Format(rateCardDateLayout) {
+		errors.addErrorf("Rate card %s is valid from %s", card, card.ValidFrom.Format(rateCardDateLayout))
+	}
+	if !card.ValidUntil.IsZero() && day > card.ValidUntil.Format(rateCardDateLayout) {
+		errors.addErrorf("Rate card %s expired on %s", card, card.ValidUntil.Format(rateCardDateLayout))
+	}
+	res := p.withRateCard(card, errors)
+	res.Scenarios = nil
+	for _, scenario := range p.Scenarios {
+		scenarioErrors := &ProjectParseError{}
+		scenario.Project = scenario.Project.withRateCard(card, scenarioErrors)
+		errors.addPrefixed("Scenario "+scenario.Name+": ", scenarioErrors)
+		res.Scenarios = append(res.Scenarios, scenario)
+	}
+	errors.pos = Pos{}
+	if !errors.hasErrors() {
+		return res, nil
+	}
+	return res, errors
+}
+
+func (p Project) withRateCard(card RateCard, errors *ProjectParseError) Project {
+	res := p
+	res.RateCard = card.String()
+	res.Team = nil
+	if p.Currency == CurrencyUnknown {
+		errors.addError("Rate card needs the currency of the project")
+	}
+	var missing []string
+	for _, r := range p.Team {
+		role, exists := card.role(r)
+		// 0 is not set for the resources built in Go
+		if r.rateInherited || r.Rate == 0 && !r.rateGiven {
+			if rate, hasRate := role.Rates[p.Currency]; hasRate {
+				r.Rate = rate
+				r.rateInherited = true
+			} else if r.Rate == 0 && p.Currency != CurrencyUnknown {
+				// the rate of the registered role is kept if the card has none
+				missing = append(missing, r.Id)
+			}
+		}
+		if exists && role.Title != "" && (r.titleFromCard || r.Title == "" || r.Title == defaultTitle(r.Id)) {
+			r.Title = role.Title
+			r.titleFromCard = true
+		}
+		res.Team = append(res.Team, r)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		errors.addErrorf("Rate card %s has no %s rates for: %s", card, p.Currency, strings.Join(missing, ", "))
+	}
+	return res
+}
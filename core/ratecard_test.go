@@ -0,0 +1,159 @@
+package core
+
+import (
+	"bytes"
+	"github.com/xuri/excelize/v2"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rateCardData = `
+name: acme
+version: "2024.2"
+valid_from: 2024-01-01
+valid_until: 2024-12-31
+roles:
+  be:
+    title: Backend Engineer
+    rates: {usd: 40, eur: 37}
+  be.senior:
+    rates: {usd: 55}
+  qa:
+    rates: {usd: 25}
+`
+
+func TestWithRateCard(t *testing.T) {
+	card, err := RateCardFromYaml(rateCardData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj := mustNoError(t, `
+currency usd
+team
+be.junior cnt=2
+be.senior cnt=1
+qa cnt=1 rate=20 formula=be*0.3
+tasks
+API | Login | be=2
+scenario eur
+currency eur
+`)
+	proj, err = proj.WithRateCard(card, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err == nil || !strings.Contains(err.Error(), "Scenario eur: Rate card acme v2024.2 has no EUR rates for: be.senior") {
+		t.Fatalf("must report the missing rates of the scenario: %v", err)
+	}
+	team := proj.TeamAsMap()
+	if team["be.junior"].Rate != 40 || team["be.senior"].Rate != 55 || team["qa"].Rate != 20 {
+		t.Fatalf("the card rates must be taken unless set: %v", proj.Team)
+	}
+	if team["be.junior"].Title != "Backend Engineer (junior)" || team["be.senior"].Title != "Back dev (senior)" {
+		t.Fatalf("the card titles must replace the default ones: %v", proj.Team)
+	}
+	if proj.RateCard != "acme v2024.2" || proj.Scenarios[0].Project.TeamAsMap()["be.junior"].Rate != 37 {
+		t.Fatalf("wrong card of the project or the scenario: %+v", proj)
+	}
+
+	// another card replaces the rates taken from the first one
+	other := card
+	other.Roles = map[string]RateCardRole{"be": {Rates: map[Currency]float64{Usd: 42}}, "be.senior": {Rates: map[Currency]float64{Usd: 60}}}
+	proj.Scenarios = nil
+	proj, err = proj.WithRateCard(other, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || proj.TeamAsMap()["be.junior"].Rate != 42 || proj.TeamAsMap()["qa"].Rate != 20 {
+		t.Fatalf("wrong rates from the other card: %v %v", proj.Team, err)
+	}
+
+	if _, err := proj.WithRateCard(card, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); err == nil || !strings.Contains(err.Error(), "expired on 2024-12-31") {
+		t.Fatalf("must report the expired card: %v", err)
+	}
+}
+
+func TestRateCardKeepsZeroRate(t *testing.T) {
+	card, err := RateCardFromYaml(rateCardData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj := mustNoError(t, `
+currency usd
+team
+be cnt=1 rate=0 title=Intern
+ds cnt=1 rate=0
+tasks
+API | Login | be=2 ds=1
+`)
+	proj, err = proj.WithRateCard(card, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("the card has no ds, but its rate is set: %v", err)
+	}
+	if team := proj.TeamAsMap(); team["be"].Rate != 0 || team["ds"].Rate != 0 {
+		t.Fatalf("the rates set to 0 must be kept: %v", proj.Team)
+	}
+}
+
+func TestRateCardDirective(t *testing.T) {
+	files := map[string]string{"shared/rates.yml": "version: \"1\"\nroles:\n  be:\n    rates: {usd: 40}\n"}
+	proj, read, err := projectFromFileText("main.txt", "currency usd\nrate_card shared/rates.yml\nteam\nbe cnt=1\ntasks\nA | B | be=1\n", readFrom(files), ProjectFileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proj.ResourceById("be").Rate != 40 || proj.RateCard != "rates v1" || strings.Join(read, ",") != "main.txt,shared/rates.yml" {
+		t.Fatalf("wrong rate card: %+v %v", proj, read)
+	}
+	var buf bytes.Buffer
+	if err := GenerateExcelTo(&buf, proj, ExcelOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := f.GetRows(SheetParameters); !strings.Contains(strings.Join(rows[6], ","), "Rate card,rates v1") {
+		t.Fatalf("the workbook must note the card: %v", rows)
+	}
+
+	mustBeError(t, "rate_card rates.yml\n")
+	if _, _, err := projectFromFileText("main.txt", "currency usd\nrate_card none.yml\n", readFrom(files), ProjectFileOptions{}); err == nil ||
+		!strings.Contains(err.Error(), "main.txt:2: Can't read the rate card") {
+		t.Fatalf("must report the missing card: %v", err)
+	}
+	if _, err := RateCardFromYaml("roles:\n  be:\n    rates: {rub: 1}\n"); err == nil {
+		t.Fatalf("must reject unknown currency")
+	}
+	if _, err := RateCardFromYaml("valid_from: 1.1.2024\n"); err == nil {
+		t.Fatalf("must reject wrong date")
+	}
+}
+
+func TestRateCardOfFileOptions(t *testing.T) {
+	files := map[string]string{
+		"shared/rates.yml": "version: \"1\"\nroles:\n  be:\n    rates: {usd: 40}\n",
+		"shared/old.yml":   "version: \"0\"\nvalid_to: 2000-12-31\nroles:\n  be:\n    rates: {usd: 30}\n",
+		"shared/other.yml": "version: \"2\"\nroles:\n  be:\n    rates: {usd: 50}\n",
+	}
+	yamlProj := "currency: usd\nrate_card: ../shared/rates.yml\nteam:\n  be: {cnt: 1}\ntasks:\n  - {cat: A, title: B, be: 1}\n"
+	proj, read, err := projectFromFileText("yaml/proj.yml", yamlProj, readFrom(files), ProjectFileOptions{Format: formatYaml})
+	if err != nil || proj.ResourceById("be").Rate != 40 || strings.Join(read, ",") != "yaml/proj.yml,shared/rates.yml" {
+		t.Fatalf("the card must be relative to the YAML file: %v %v %v", proj.Team, read, err)
+	}
+	jsonProj := `{"currency": "usd", "rate_card": "../shared/rates.yml", "team": {"be": {"cnt": 1}}, "tasks": [{"cat": "A", "title": "B", "be": 1}]}`
+	if proj, _, err := projectFromFileText("json/proj.json", jsonProj, readFrom(files), ProjectFileOptions{Format: formatJson}); err != nil || proj.ResourceById("be").Rate != 40 {
+		t.Fatalf("the card must be relative to the JSON file: %v %v", proj.Team, err)
+	}
+	if _, _, err := projectFromFileText("proj.json", "{", readFrom(files), ProjectFileOptions{Format: formatJson}); err == nil || !strings.Contains(err.Error(), "Wrong JSON") {
+		t.Fatalf("must report the JSON syntax: %v", err)
+	}
+
+	// the card given instead of the expired one of the directive
+	other, err := rateCardNamed("shared/other.yml", files["shared/other.yml"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := "currency usd\nrate_card shared/old.yml\nteam\nbe cnt=1\ntasks\nA | B | be=1\n"
+	if _, _, err := projectFromFileText("main.txt", expired, readFrom(files), ProjectFileOptions{}); err == nil {
+		t.Fatalf("the card of the directive must be expired")
+	}
+	proj, read, err = projectFromFileText("main.txt", expired, readFrom(files), ProjectFileOptions{RateCard: &other})
+	if err != nil || proj.ResourceById("be").Rate != 50 || strings.Join(read, ",") != "main.txt" {
+		t.Fatalf("the given card must replace the one of the directive: %v %v %v", proj.Team, read, err)
+	}
+}
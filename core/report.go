@@ -0,0 +1,97 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteScenariosComparison prints cost and duration of the scenarios side by side
+func WriteScenariosComparison(w io.Writer, project Project, results []ScenarioResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Scenario\tEfforts (%vs)\tWith Risks (%vs)\tTeam\tCost\tDuration (mths)\tWith Risks (mths)\t\n", project.TimeUnit, project.TimeUnit)
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%d\t%s\t%.1f\t%.1f\t\n", r.Name,
+			r.Result.Efforts, r.Result.EffortsWithRisks, teamSize(r.Result.Team),
+			project.Currency.Format(r.Result.Cost), r.Result.Duration, r.Result.DurationWithRisks)
+	}
+	return tw.Flush()
+}
+
+func teamSize(team []Resource) int {
+	res := 0
+	for _, r := range team {
+		res += r.Count
+	}
+	return res
+}
+
+// WriteSensitivityTable prints the tornado table of the sensitivity analysis
+func WriteSensitivityTable(w io.Writer, project Project, analysis SensitivityAnalysis) error {
+	fmt.Fprintf(w, "Sensitivity (±%.0f%%), base cost %s, duration %.1f mths\n",
+		analysis.Range*100, project.Currency.Format(analysis.Base.Cost), analysis.Base.DurationWithRisks)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Input\tLow\tHigh\tCost Low\tCost High\tSwing\tDuration Low\tDuration High\t\n")
+	for _, item := range analysis.Items {
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%s\t%s\t%s\t%.1f\t%.1f\t\n", item.Input, item.Low, item.High,
+			project.Currency.Format(item.CostLow), project.Currency.Format(item.CostHigh),
+			project.Currency.Format(item.CostSwing()), item.DurationLow, item.DurationHigh)
+	}
+	return tw.Flush()
+}
+
+// WriteSummary prints the totals of the project and the share of every team member
+func WriteSummary(w io.Writer, project Project, result ProjectCalculationResult) error {
+	name := project.Name
+	if name == "" {
+		name = "Project"
+	}
+	fmt.Fprintf(w, "%s: %s, %.1f %ss (%.1f with risks), %.1f mths (%.1f with risks), team of %d\n", name,
+		project.Currency.Format(result.Cost), result.Efforts, project.TimeUnit, result.EffortsWithRisks,
+		result.Duration, result.DurationWithRisks, teamSize(result.Team))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Resource\tCount\tRate\tEfforts (%vs)\tWith Risks (%vs)\tCost\t\n", project.TimeUnit, project.TimeUnit)
+	for _, r := range result.Resources {
+		title := r.Title
+		if title == "" {
+			title = r.Id
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%.1f\t%.1f\t%s\t\n", title, r.Count, project.Currency.Format(r.Rate),
+			r.Efforts, r.EffortsWithRisks, project.Currency.Format(r.Cost))
+	}
+	return tw.Flush()
+}
+
+// WriteSections prints the assumptions, exclusions and notes of the project as numbered lists
+func WriteSections(w io.Writer, project Project) error {
+	for i, section := range project.Sections() {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s:\n", section.Title)
+		for j, item := range section.Items {
+			if _, err := fmt.Fprintf(w, "%3d. %s\n", j+1, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteTagsReport prints the efforts and cost of the tasks by their tags, a task with several tags counts in each of them
+func WriteTagsReport(w io.Writer, project Project, result ProjectCalculationResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "Tag\tTasks\tEfforts (%vs)\tWith Risks (%vs)\tCost\t\n", project.TimeUnit, project.TimeUnit)
+	for _, tc := range project.CalculateTags(result) {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f\t%s\t\n", tagTitle(tc.Tag), tc.Tasks, tc.Efforts, tc.EffortsWithRisks,
+			project.Currency.Format(tc.Cost))
+	}
+	return tw.Flush()
+}
+
+func tagTitle(tag string) string {
+	if tag == "" {
+		return "(untagged)"
+	}
+	return tag
+}
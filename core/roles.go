@@ -0,0 +1,81 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+// RoleDef is the organization-wide definition of a role like "bc" or of a role with seniority level
+// like "be.senior". The team members of the role take its title, rate and formula unless they have their own.
+type RoleDef struct {
+	Id      string
+	Title   string
+	Rate    float64 // 0 if not set
+	Formula string  // makes the team members of the role derived by default
+}
+
+// registeredRoles are the definitions of RegisterRoles, the titles go to standardResourceTypes
+var registeredRoles = map[string]RoleDef{}
+
+// RegisterRoles adds the roles to the standard ones or overrides them. It's meant to be called once
+// at the start, before any project is parsed.
+func RegisterRoles(roles []RoleDef) {
+	for _, role := range roles {
+		registeredRoles[role.Id] = role
+		if role.Title != "" {
+			standardResourceTypes[role.Id] = role.Title
+		}
+	}
+}
+
+// RolesFromYaml parses the role definitions in the form of roles.yml
+func RolesFromYaml(data string) ([]RoleDef, error) {
+	var parsed struct {
+		Roles map[string]struct {
+			Title   string  `yaml:"title"`
+			Rate    float64 `yaml:"rate"`
+			Formula string  `yaml:"formula"`
+		} `yaml:"roles"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("wrong roles: %w", err)
+	}
+	var res []RoleDef
+	for id, role := range parsed.Roles {
+		if role.Rate < 0 {
+			return nil, fmt.Errorf("rate must be >= 0 for the role %s: %v", id, role.Rate)
+		}
+		res = append(res, RoleDef{Id: id, Title: role.Title, Rate: role.Rate, Formula: role.Formula})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res, nil
+}
+
+// RolesFromFile reads the role definitions to register
+func RolesFromFile(path string) ([]RoleDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := RolesFromYaml(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return roles, nil
+}
+
+// registeredRole finds the definition of the team member, falling back to the role without the seniority level
+func registeredRole(id string) (RoleDef, bool) {
+	if role, exists := registeredRoles[id]; exists {
+		return role, true
+	}
+	role, _, _ := strings.Cut(id, levelSeparator)
+	def, exists := registeredRoles[role]
+	return def, exists
+}
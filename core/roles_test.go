@@ -0,0 +1,60 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestRegisterRoles(t *testing.T) {
+	roles, err := RolesFromYaml(`
+roles:
+  bc:
+    title: Blockchain Engineer
+    rate: 80
+  be.senior:
+    title: Staff engineer
+  qa:
+    formula: bc*0.3
+    rate: 20
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	savedTitles := map[string]string{}
+	for k, v := range standardResourceTypes {
+		savedTitles[k] = v
+	}
+	defer func() {
+		standardResourceTypes = savedTitles
+		registeredRoles = map[string]RoleDef{}
+	}()
+	RegisterRoles(roles)
+
+	project := mustNoError(t, `
+currency usd
+team
+bc.junior cnt=1
+bc.senior cnt=1 rate=100
+be.senior cnt=1 rate=50
+qa cnt=1
+tasks
+Chain | Contracts | bc=5 be=1
+`)
+	team := project.TeamAsMap()
+	if team["bc.junior"].Rate != 80 || team["bc.junior"].Title != "Blockchain Engineer (junior)" || team["bc.senior"].Rate != 100 {
+		t.Fatalf("the registered rate and title must be taken: %v", project.Team)
+	}
+	if team["be.senior"].Title != "Staff engineer" || team["qa"].Formula != "bc*0.3" || team["qa"].Rate != 20 {
+		t.Fatalf("the registered level and formula must be taken: %v", project.Team)
+	}
+
+	// the rate card takes the place of the registered rate
+	card := RateCard{Name: "acme", Roles: map[string]RateCardRole{"bc": {Rates: map[Currency]float64{Usd: 90}}}}
+	withCard, err := project.WithRateCard(card, card.ValidFrom)
+	if err != nil || withCard.TeamAsMap()["bc.junior"].Rate != 90 || withCard.TeamAsMap()["qa"].Rate != 20 {
+		t.Fatalf("wrong rates with the card: %v %v", withCard.Team, err)
+	}
+
+	if _, err := RolesFromYaml("roles:\n  bc:\n    rate: -1\n"); err == nil {
+		t.Fatalf("must reject negative rate")
+	}
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+const DefaultSensitivityRange = 0.2
+
+type SensitivityOptions struct {
+	Range float64 // relative variation of every input, 0.2 means ±20%
+}
+
+// SensitivityItem is the effect of varying a single input on the project totals
+type SensitivityItem struct {
+	Input                     string
+	Low, High                 float64 // values of the input
+	CostLow, CostHigh         float64
+	DurationLow, DurationHigh float64 // months, with risks
+}
+
+func (si SensitivityItem) CostSwing() float64 {
+	return math.Abs(si.CostHigh - si.CostLow)
+}
+func (si SensitivityItem) DurationSwing() float64 {
+	return math.Abs(si.DurationHigh - si.DurationLow)
+}
+
+type SensitivityAnalysis struct {
+	Range float64
+	Base  ProjectCalculationResult
+	Items []SensitivityItem // ranked by the effect on cost, then on duration
+}
+
+// AnalyzeSensitivity varies risk factors, rates, acceptance percent and efforts of tasks one by one
+// to find out which of them move the totals most
+func AnalyzeSensitivity(project Project, opts SensitivityOptions) SensitivityAnalysis {
+	if opts.Range == 0 {
+		opts.Range = DefaultSensitivityRange
+	}
+	base := project.clone()
+	res := SensitivityAnalysis{Range: opts.Range, Base: base.Calculate()}
+
+	// the values are kept within the ones the project can have, like the risk factors not below 1.
+	// The inputs at 0, like no acceptance, don't move the totals whatever the range.
+	vary := func(input string, value, min, max float64, apply func(p *Project, v float64)) {
+		if value == 0 {
+			return
+		}
+		item := SensitivityItem{Input: input, Low: math.Max(min, value*(1-opts.Range)), High: math.Min(max, value*(1+opts.Range))}
+		low := project.clone()
+		apply(&low, item.Low)
+		lowRes := low.Calculate()
+		high := project.clone()
+		apply(&high, item.High)
+		highRes := high.Calculate()
+		item.CostLow, item.CostHigh = lowRes.Cost, highRes.Cost
+		item.DurationLow, item.DurationHigh = lowRes.DurationWithRisks, highRes.DurationWithRisks
+		res.Items = append(res.Items, item)
+	}
+
+	usedRisks := map[string]bool{}
+	for _, t := range project.Tasks {
+		usedRisks[t.Risk] = usedRisks[t.Risk] || !t.Optional
+	}
+	for _, risk := range RiskLabels(project.Risks) {
+		if !usedRisks[risk] {
+			// no task in the totals has it
+			continue
+		}
+		risk := risk
+		vary("Risk "+risk, project.Risks[risk], 1, math.Inf(1), func(p *Project, v float64) {
+			p.Risks[risk] = v
+		})
+	}
+	for i, r := range project.Team {
+		i := i
+		vary("Rate of "+r.Title, r.Rate, 0, math.Inf(1), func(p *Project, v float64) {
+			p.Team[i].Rate = v
+		})
+	}
+	vary("Cleanup & acceptance", project.AcceptancePercent, 0, 100, func(p *Project, v float64) {
+		p.AcceptancePercent = v
+	})
+	for i, t := range project.Tasks {
+		if t.Optional {
+			// not in the totals
+			continue
+		}
+		i := i
+		total := 0.0
+		for _, effort := range t.Work {
+			total += effort
+		}
+		vary(fmt.Sprintf("Efforts of %s | %s", t.Category, t.Title), total, 0, math.Inf(1), func(p *Project, v float64) {
+			for resId, effort := range p.Tasks[i].Work {
+				p.Tasks[i].Work[resId] = effort * v / total
+			}
+		})
+	}
+
+	sort.SliceStable(res.Items, func(i, j int) bool {
+		ii, ij := res.Items[i], res.Items[j]
+		if ii.CostSwing() != ij.CostSwing() {
+			return ii.CostSwing() > ij.CostSwing()
+		}
+		return ii.DurationSwing() > ij.DurationSwing()
+	})
+	return res
+}
+
+// clone copies the project deep enough to vary its inputs independently
+func (p Project) clone() Project {
+	res := p
+	res.Team = append([]Resource{}, p.Team...)
+	res.Risks = map[string]float64{}
+	for k, v := range p.Risks {
+		res.Risks[k] = v
+	}
+	res.Tasks = make([]Task, len(p.Tasks))
+	for i, t := range p.Tasks {
+		res.Tasks[i] = t
+		res.Tasks[i].Work = map[string]float64{}
+		for k, v := range t.Work {
+			res.Tasks[i].Work[k] = v
+		}
+	}
+	return res
+}
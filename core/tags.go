@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	tagsKey      = "tags"
+	tagSeparator = ","
+)
+
+// ParseTags splits the value like `mvp,optional`, the duplicates and empty ones are dropped
+func ParseTags(value string) []string {
+	var res []string
+	for _, tag := range strings.Split(value, tagSeparator) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsString(res, tag) {
+			res = append(res, tag)
+		}
+	}
+	return res
+}
+
+// HasTag tells if the task is tagged so
+func (t Task) HasTag(tag string) bool {
+	return containsString(t.Tags, tag)
+}
+
+func (t Task) hasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if t.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tags lists the tags of all the tasks, sorted
+func (p Project) Tags() []string {
+	var res []string
+	for _, task := range p.Tasks {
+		for _, tag := range task.Tags {
+			if !containsString(res, tag) {
+				res = append(res, tag)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// WithTags keeps the tasks having any of the include tags, all if none, and drops the ones having any of the exclude tags.
+// The scenarios are filtered the same way. The tags no task has are reported, likely they are misspelled.
+func (p Project) WithTags(include, exclude []string) (Project, error) {
+	var unknown []string
+	for _, tag := range append(append([]string{}, include...), exclude...) {
+		if !containsString(p.Tags(), tag) && !containsString(unknown, tag) {
+			unknown = append(unknown, tag)
+		}
+	}
+	res := p.withTags(include, exclude)
+	res.Scenarios = nil
+	for _, scenario := range p.Scenarios {
+		scenario.Project = scenario.Project.withTags(include, exclude)
+		res.Scenarios = append(res.Scenarios, scenario)
+	}
+	if len(unknown) > 0 {
+		return res, fmt.Errorf("no tasks tagged %s", strings.Join(unknown, ", "))
+	}
+	return res, nil
+}
+
+func (p Project) withTags(include, exclude []string) Project {
+	res := p
+	res.Tasks = nil
+	for _, task := range p.Tasks {
+		if (len(include) == 0 || task.hasAnyTag(include)) && !task.hasAnyTag(exclude) {
+			res.Tasks = append(res.Tasks, task)
+		}
+	}
+	return res
+}
+
+// TagCalculation sums up the tasks of a tag like CategoryCalculation, the task with several tags counts in each of them
+type TagCalculation struct {
+	Tag              string // "" for the tasks without tags
+	Tasks            int
+	Efforts          float64 // in project time units
+	EffortsWithRisks float64
+	Cost             float64
+}
+
+// CalculateTags groups the tasks of the project calculated into result by their tags, sorted, the untagged ones go last.
+// The optional tasks are left out like in the totals.
+func (p Project) CalculateTags(result ProjectCalculationResult) []TagCalculation {
+	var res []TagCalculation
+	for _, tag := range append(p.Tags(), "") {
+		tc := TagCalculation{Tag: tag}
+		for _, task := range p.Tasks {
+			if task.Optional || (tag == "" && len(task.Tags) > 0) || (tag != "" && !task.HasTag(tag)) {
+				continue
+			}
+			calc := p.CalculateTask(task, result)
+			tc.Tasks++
+			tc.Efforts += calc.Efforts
+			tc.EffortsWithRisks += calc.EffortsWithRisks
+			tc.Cost += calc.Cost
+		}
+		if tc.Tasks > 0 {
+			res = append(res, tc)
+		}
+	}
+	return res
+}
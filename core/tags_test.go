@@ -0,0 +1,77 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const projWithTags = `
+currency usd
+time_unit day
+team
+be cnt=1 rate=40
+tasks
+API | Login  | be=3 tags=mvp,backend
+API | Orders | be=5 tags="backend, client"
+UI  | Theme  | be=2
+scenario lean
+exclude UI
+`
+
+func TestTags(t *testing.T) {
+	project := mustNoError(t, projWithTags)
+	if strings.Join(project.Tasks[1].Tags, ",") != "backend,client" || len(project.Tasks[0].Work) != 1 {
+		t.Fatalf("wrong tags: %+v", project.Tasks)
+	}
+	if strings.Join(project.Tags(), ",") != "backend,client,mvp" {
+		t.Fatalf("wrong tags: %v", project.Tags())
+	}
+
+	filtered, err := project.WithTags([]string{"backend"}, []string{"client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered.Tasks) != 1 || filtered.Tasks[0].Title != "Login" || len(filtered.Scenarios[0].Project.Tasks) != 1 {
+		t.Fatalf("wrong filtering: %+v", filtered.Tasks)
+	}
+	if filtered, _ := project.WithTags(nil, []string{"mvp"}); len(filtered.Tasks) != 2 {
+		t.Fatalf("only the excluded must be dropped: %+v", filtered.Tasks)
+	}
+	if _, err := project.WithTags([]string{"mpv"}, nil); err == nil {
+		t.Fatalf("must be error for the unknown tag")
+	}
+
+	tags := project.CalculateTags(project.Calculate())
+	if len(tags) != 4 || tags[0].Tag != "backend" || tags[0].Tasks != 2 || tags[3].Tag != "" {
+		t.Fatalf("wrong tags calculation: %+v", tags)
+	}
+	assertFloat(t, "backend efforts", 8, tags[0].Efforts)
+	assertFloat(t, "mvp cost", 3*8*40, tags[2].Cost)
+
+	var buf bytes.Buffer
+	if err := WriteTagsReport(&buf, project, project.Calculate()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "(untagged)") {
+		t.Fatalf("must list the untagged tasks:\n%s", buf.String())
+	}
+}
+
+func TestTagsYaml(t *testing.T) {
+	project := mustNoError(t, projWithTags)
+	yml, err := ProjectToYaml(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(yml, "tags: [mvp, backend]") {
+		t.Fatalf("wrong tags:\n%s", yml)
+	}
+	parsed, err := ProjectFromYaml(yml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(parsed.Tasks[0].Tags, ",") != "mvp,backend" {
+		t.Fatalf("wrong tags: %+v", parsed.Tasks[0])
+	}
+}
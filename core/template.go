@@ -0,0 +1,171 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ProjectTemplate is the starting point of a new estimate: the team of the standard roles
+// with the usual QA and PM formulas, and the categories with the typical tasks
+type ProjectTemplate struct {
+	Name        string
+	Description string
+	team        []Resource
+	tasks       []Task // efforts in days
+}
+
+// TemplateOptions fill in the directives of the project made from a template
+type TemplateOptions struct {
+	Name     string
+	Author   string
+	Currency Currency // Usd if not set
+	TimeUnit TimeUnit // Day if not set
+}
+
+var ProjectTemplates = []ProjectTemplate{
+	{
+		Name:        "web",
+		Description: "web application: backend, frontend and design",
+		team: []Resource{
+			{Id: "be", Count: 2, Rate: 40},
+			{Id: "fe", Count: 1, Rate: 35},
+			{Id: "ds", Count: 1, Rate: 30},
+			{Id: "qa", Count: 1, Rate: 25, Formula: "(be+fe)*0.3"},
+			{Id: "pm", Count: 1, Rate: 45, Formula: "(be+fe+ds)*0.15"},
+		},
+		tasks: []Task{
+			{Category: "Setup", Title: "Repository, CI and environments", Work: map[string]float64{"be": 2, "fe": 1}},
+			{Category: "Design", Title: "Wireframes", Risk: "medium", Work: map[string]float64{"ds": 3}},
+			{Category: "Design", Title: "UI kit and screens", Risk: "medium", Work: map[string]float64{"ds": 5}},
+			{Category: "Backend", Title: "Authentication", Risk: "low", Work: map[string]float64{"be": 3}},
+			{Category: "Backend", Title: "Domain model and API", Risk: "high", Work: map[string]float64{"be": 10}},
+			{Category: "Frontend", Title: "Layout and navigation", Risk: "low", Work: map[string]float64{"fe": 3}},
+			{Category: "Frontend", Title: "Pages", Risk: "medium", Work: map[string]float64{"fe": 10}},
+			{Category: "Release", Title: "Deployment to production", Risk: "medium", Work: map[string]float64{"be": 2}},
+		},
+	},
+	{
+		Name:        "mobile",
+		Description: "iOS and Android apps with their backend",
+		team: []Resource{
+			{Id: "ios", Count: 1, Rate: 45},
+			{Id: "droid", Count: 1, Rate: 45},
+			{Id: "be", Count: 1, Rate: 40},
+			{Id: "ds", Count: 1, Rate: 30},
+			{Id: "qa", Count: 1, Rate: 25, Formula: "(ios+droid+be)*0.3"},
+			{Id: "pm", Count: 1, Rate: 45, Formula: "(ios+droid+be+ds)*0.15"},
+		},
+		tasks: []Task{
+			{Category: "Setup", Title: "Projects, CI and signing", Work: map[string]float64{"ios": 1, "droid": 1, "be": 1}},
+			{Category: "Design", Title: "Screens for both platforms", Risk: "medium", Work: map[string]float64{"ds": 8}},
+			{Category: "Backend", Title: "API for the apps", Risk: "high", Work: map[string]float64{"be": 10}},
+			{Category: "Backend", Title: "Push notifications", Risk: "medium", Work: map[string]float64{"be": 2}},
+			{Category: "iOS", Title: "Screens", Risk: "medium", Work: map[string]float64{"ios": 12}},
+			{Category: "Android", Title: "Screens", Risk: "medium", Work: map[string]float64{"droid": 12}},
+			{Category: "Release", Title: "App Store and Google Play", Risk: "high", Work: map[string]float64{"ios": 1, "droid": 1}},
+		},
+	},
+	{
+		Name:        "api",
+		Description: "backend API without UI",
+		team: []Resource{
+			{Id: "be", Count: 2, Rate: 40},
+			{Id: "do", Count: 1, Rate: 45},
+			{Id: "qa", Count: 1, Rate: 25, Formula: "be*0.25"},
+			{Id: "pm", Count: 1, Rate: 45, Formula: "(be+do)*0.1"},
+		},
+		tasks: []Task{
+			{Category: "Setup", Title: "Repository and CI", Work: map[string]float64{"be": 1, "do": 1}},
+			{Category: "API", Title: "Authentication", Risk: "low", Work: map[string]float64{"be": 3}},
+			{Category: "API", Title: "Endpoints", Risk: "medium", Work: map[string]float64{"be": 15}},
+			{Category: "API", Title: "Documentation", Risk: "low", Work: map[string]float64{"be": 2}},
+			{Category: "Integrations", Title: "Third-party services", Risk: "high", Work: map[string]float64{"be": 5}},
+			{Category: "Infrastructure", Title: "Environments and monitoring", Risk: "medium", Work: map[string]float64{"do": 5}},
+			{Category: "Release", Title: "Load testing and launch", Risk: "medium", Work: map[string]float64{"be": 2, "do": 2}},
+		},
+	},
+	{
+		Name:        "data",
+		Description: "data pipeline: ingestion, transformation and reporting",
+		team: []Resource{
+			{Id: "be", Count: 2, Rate: 45},
+			{Id: "do", Count: 1, Rate: 45},
+			{Id: "ba", Count: 1, Rate: 35},
+			{Id: "qa", Count: 1, Rate: 25, Formula: "be*0.2"},
+			{Id: "pm", Count: 1, Rate: 45, Formula: "(be+do+ba)*0.1"},
+		},
+		tasks: []Task{
+			{Category: "Setup", Title: "Repository, CI and environments", Work: map[string]float64{"be": 1, "do": 2}},
+			{Category: "Analysis", Title: "Sources and metrics", Risk: "medium", Work: map[string]float64{"ba": 5}},
+			{Category: "Ingestion", Title: "Connectors to the sources", Risk: "high", Work: map[string]float64{"be": 8}},
+			{Category: "Transformation", Title: "Cleaning and modelling", Risk: "high", Work: map[string]float64{"be": 10}},
+			{Category: "Storage", Title: "Warehouse schema", Risk: "medium", Work: map[string]float64{"be": 3, "do": 2}},
+			{Category: "Reporting", Title: "Dashboards", Risk: "medium", Work: map[string]float64{"ba": 5, "be": 2}},
+			{Category: "Operations", Title: "Scheduling and alerting", Risk: "medium", Work: map[string]float64{"do": 4}},
+		},
+	},
+}
+
+func TemplateByName(name string) (ProjectTemplate, bool) {
+	for _, t := range ProjectTemplates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return ProjectTemplate{}, false
+}
+
+// Render gives the text of the new project
+func (t ProjectTemplate) Render(opts TemplateOptions) string {
+	if opts.Currency == CurrencyUnknown {
+		opts.Currency = Usd
+	}
+	if opts.TimeUnit == TimeUnitUnknown {
+		opts.TimeUnit = Day
+	}
+	var b strings.Builder
+	if opts.Name != "" {
+		fmt.Fprintf(&b, "project %s\n", opts.Name)
+	}
+	if opts.Author != "" {
+		fmt.Fprintf(&b, "author %s\n", opts.Author)
+	}
+	fmt.Fprintf(&b, "\ncurrency %s\ntime_unit %s\nacceptance_percent 10\n\n", strings.ToLower(opts.Currency.String()), opts.TimeUnit)
+
+	risks := StandardRisks()
+	b.WriteString("risks")
+	for _, risk := range RiskLabels(risks) {
+		fmt.Fprintf(&b, " %s=%s", risk, strconv.FormatFloat(risks[risk], 'f', -1, 64))
+	}
+
+	fmt.Fprintf(&b, "\n\n# %s, rates are per hour\nteam\n", t.Description)
+	for _, r := range t.team {
+		fmt.Fprintf(&b, "%s cnt=%d rate=%s", r.Id, r.Count, strconv.FormatFloat(r.Rate, 'f', -1, 64))
+		if r.Formula != "" {
+			fmt.Fprintf(&b, " formula=%s", quoteIfNeeded(r.Formula))
+		}
+		b.WriteString("\n")
+	}
+
+	daysToUnit := float64(Day.ToHours()) / float64(opts.TimeUnit.ToHours())
+	fmt.Fprintf(&b, "\n# efforts in %ss, replace the examples with the tasks of the project\ntasks\n", opts.TimeUnit)
+	for _, task := range t.tasks {
+		fmt.Fprintf(&b, "%s | %s |", task.Category, task.Title)
+		for _, resId := range sortedKeys(task.Work) {
+			effort := math.Round(task.Work[resId]*daysToUnit*100) / 100
+			fmt.Fprintf(&b, " %s=%s", resId, strconv.FormatFloat(effort, 'f', -1, 64))
+		}
+		if task.Risk != "" {
+			fmt.Fprintf(&b, " %s=%s", risksKey, task.Risk)
+		}
+		b.WriteString("\n")
+	}
+
+	formatted, err := FormatProject(b.String())
+	if err != nil {
+		panic(err) // the templates are tested to render valid projects
+	}
+	return formatted
+}
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplates(t *testing.T) {
+	for _, template := range ProjectTemplates {
+		text := template.Render(TemplateOptions{Name: "New " + template.Name, Author: "me@example.com", Currency: Eur})
+		project, err := ProjectFromString(text)
+		if err != nil {
+			t.Fatalf("template %s must be valid: %v\n%s", template.Name, err, text)
+		}
+		if project.Name != "New "+template.Name || project.Currency != Eur || project.TimeUnit != Day {
+			t.Fatalf("template %s must apply the options: %v", template.Name, project)
+		}
+		if len(project.Risks) != len(StandardRisks()) || project.Calculate().Cost <= 0 {
+			t.Fatalf("template %s must have standard risks and cost", template.Name)
+		}
+		if formatted, _ := FormatProject(text); formatted != text {
+			t.Fatalf("template %s must be formatted:\n%s", template.Name, text)
+		}
+	}
+}
+
+func TestTemplateTimeUnit(t *testing.T) {
+	template, _ := TemplateByName("api")
+	text := template.Render(TemplateOptions{TimeUnit: Hr})
+	if !strings.Contains(text, "| be=24 risks=low") {
+		t.Fatalf("efforts must be in hours:\n%s", text)
+	}
+	if _, found := TemplateByName("nope"); found {
+		t.Fatalf("must not find unknown template")
+	}
+}
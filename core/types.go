@@ -7,8 +7,6 @@ import (
 	"strings"
 )
 
-// TODO validate Project model: 1. correct resources in tasks 2. correct risks etc.
-
 type Project struct {
 	Name              string
 	Author            string
@@ -19,6 +17,45 @@ type Project struct {
 	DesiredDuration   Duration // This will be treated as including risks
 	Risks             map[string]float64
 	Tasks             []Task
+	Scenarios         []Scenario
+	RateCard          string         // the rate card the team rates are taken from, like "rates v2024.2"
+	Assumptions       []string       // the project-wide ones, the ones of the tasks are in Task
+	Exclusions        []string       // what the estimate doesn't include
+	Notes             []string       // like the payment terms
+	directivesPos     map[string]Pos // directive name -> where it's set
+}
+
+// Pos is the position in the project description, zero if unknown like for the projects built from Go code
+type Pos struct {
+	File string // set for the projects read from files, which may include other files
+	Line int    // 1-based
+}
+
+// Scenario is a variant of the project with some directives, team properties or tasks overridden
+type Scenario struct {
+	Name    string
+	Project Project
+}
+
+// ProjectSection is the list of the project-wide text, like the assumptions
+type ProjectSection struct {
+	Title string
+	Items []string
+}
+
+// Sections lists the assumptions, exclusions and notes the way the reports show them, the empty ones are skipped
+func (p Project) Sections() []ProjectSection {
+	var res []ProjectSection
+	for _, section := range []ProjectSection{
+		{Title: "Assumptions", Items: p.Assumptions},
+		{Title: "Not included", Items: p.Exclusions},
+		{Title: "Notes", Items: p.Notes},
+	} {
+		if len(section.Items) > 0 {
+			res = append(res, section)
+		}
+	}
+	return res
 }
 
 func (p Project) TeamExcludingDerived() []Resource {
@@ -30,6 +67,57 @@ func (p Project) TeamExcludingDerived() []Resource {
 	}
 	return res
 }
+
+// WorkRoles returns the roles efforts in tasks are stated against. These are non-derived resources
+// with all seniority levels of a role collapsed into one.
+func (p Project) WorkRoles() []Resource {
+	res := []Resource{}
+	seen := map[string]bool{}
+	for _, r := range p.TeamExcludingDerived() {
+		role := r.Role()
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+		if r.Level() == "" {
+			res = append(res, r)
+			continue
+		}
+		title := standardResourceTypes[role]
+		if title == "" {
+			title = role
+		}
+		res = append(res, Resource{Id: role, Title: title})
+	}
+	return res
+}
+
+// ResourcesOfRole returns all the team members that share efforts stated in tasks for the role
+func (p Project) ResourcesOfRole(role string) []Resource {
+	res := []Resource{}
+	for _, r := range p.TeamExcludingDerived() {
+		if r.Role() == role {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func (p Project) hasRole(role string) bool {
+	return len(p.ResourcesOfRole(role)) > 0
+}
+
+// UsesVelocity tells if the team has seniority levels or non-default velocity,
+// so that the efforts stated in tasks are not the same as the efforts of the team members
+func (p Project) UsesVelocity() bool {
+	for _, r := range p.TeamExcludingDerived() {
+		if r.Level() != "" || r.velocity() != 1 {
+			return true
+		}
+	}
+	return false
+}
+
 func (p Project) TeamAsMap() map[string]Resource {
 	res := map[string]Resource{}
 	for _, resource := range p.Team {
@@ -51,6 +139,26 @@ type Duration struct {
 	unit     TimeUnit
 }
 
+// NewDuration makes the duration like 10 months: NewDuration(10, Month)
+func NewDuration(value float64, unit TimeUnit) Duration {
+	return Duration{duration: value, unit: unit}
+}
+
+func (d Duration) Value() float64 {
+	return d.duration
+}
+func (d Duration) Unit() TimeUnit {
+	return d.unit
+}
+
+// String gives the duration in the form ParseDuration understands, like "10mth"
+func (d Duration) String() string {
+	if d == (Duration{}) {
+		return ""
+	}
+	return strconv.FormatFloat(d.duration, 'f', -1, 64) + d.unit.String()
+}
+
 func (d Duration) ToHours() float64 {
 	return float64(d.unit.ToHours()) * d.duration
 }
@@ -64,7 +172,7 @@ func ParseDuration(str string) (Duration, error) {
 			if strings.HasSuffix(str, attemptUnit) {
 				val := str[:len(str)-len(attemptUnit)]
 				val = strings.TrimSpace(val)
-				float, err := strconv.ParseFloat(val, 32)
+				float, err := strconv.ParseFloat(val, 64)
 				if err != nil {
 					return Duration{}, err
 				}
@@ -79,18 +187,87 @@ func ParseDuration(str string) (Duration, error) {
 }
 
 type Resource struct {
-	Id      string
-	Title   string
-	Rate    float64
-	Count   int
-	Formula string
+	Pos      Pos
+	Id       string // either a role like "be" or a role with seniority level like "be.senior"
+	Title    string
+	Rate     float64
+	Count    int
+	Velocity float64 // productivity relative to the efforts stated in tasks, 1 if not set
+	Formula  string
+
+	rateInherited bool
+	rateGiven     bool // rate= of the project, even 0, the rate card doesn't replace it
+	titleFromCard bool
+}
+
+const levelSeparator = "."
+
+// Role returns the resource id without seniority level, this is what efforts in tasks refer to
+func (r Resource) Role() string {
+	role, _, _ := strings.Cut(r.Id, levelSeparator)
+	return role
+}
+
+// Level returns the seniority level of the resource, "" if not set
+func (r Resource) Level() string {
+	_, level, _ := strings.Cut(r.Id, levelSeparator)
+	return level
+}
+
+// defaultTitle is the title of a standard resource type with the seniority level if any, like "Back dev (senior)"
+func defaultTitle(id string) string {
+	if title := standardResourceTypes[id]; title != "" {
+		// the level registered on its own
+		return title
+	}
+	role, level, _ := strings.Cut(id, levelSeparator)
+	title := standardResourceTypes[role]
+	if title != "" && level != "" {
+		title += " (" + level + ")"
+	}
+	return title
+}
+
+func (r Resource) velocity() float64 {
+	if r.Velocity == 0 {
+		return 1
+	}
+	return r.Velocity
 }
 
 type Task struct {
+	Pos      Pos
 	Category string
 	Title    string
 	Risk     string
 	Work     map[string]float64 // resource -> time units
+	Tags     []string           // like "mvp", to filter and group the tasks by
+	Optional bool               // not in the totals unless switched on in the model
+
+	// what the task includes, the assumptions it's estimated under and what it doesn't include, multi-line
+	Notes       string
+	Assumptions string
+	OutOfScope  string
+}
+
+// detailsText gives the notes, assumptions and out of scope as plain text, like for the cell comment
+func (t Task) detailsText() string {
+	var parts []string
+	if t.Notes != "" {
+		parts = append(parts, t.Notes)
+	}
+	if t.Assumptions != "" {
+		parts = append(parts, "Assumptions:\n"+t.Assumptions)
+	}
+	if t.OutOfScope != "" {
+		parts = append(parts, "Out of scope:\n"+t.OutOfScope)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// HasDetails tells if the task has notes, assumptions or the out of scope text
+func (t Task) HasDetails() bool {
+	return t.Notes != "" || t.Assumptions != "" || t.OutOfScope != ""
 }
 
 func StandardRisks() map[string]float64 {
@@ -102,6 +279,14 @@ func StandardRisks() map[string]float64 {
 	}
 }
 
+// RiskFactor returns the multiplier of efforts for the risk name, task without risks is multiplied by 1
+func (p Project) RiskFactor(risk string) float64 {
+	if risk == "" {
+		return 1
+	}
+	return p.Risks[risk]
+}
+
 func RiskLabels(risks map[string]float64) []string {
 	var keys []string
 	for k := range risks {
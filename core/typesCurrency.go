@@ -1,6 +1,10 @@
 package core
 
-import "strings"
+import (
+	"math"
+	"strconv"
+	"strings"
+)
 
 type Currency uint8
 
@@ -26,6 +30,23 @@ func (c Currency) Symbol() string {
 	return currency2Symbol[c]
 }
 
+// Format renders the amount like "$12,345", matching the currency format of the Excel model
+func (c Currency) Format(v float64) string {
+	digits := strconv.FormatFloat(math.Abs(math.Round(v)), 'f', 0, 64)
+	var res []byte
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			res = append(res, ',')
+		}
+		res = append(res, digits[i])
+	}
+	sign := ""
+	if v <= -0.5 {
+		sign = "-"
+	}
+	return sign + c.Symbol() + string(res)
+}
+
 var currencyStr2Val = map[string]Currency{
 	"USD": Usd, "EUR": Eur,
 }
@@ -33,3 +33,11 @@ func checkParseIncorrect(t *testing.T, v string) {
 		t.Fatal("should error")
 	}
 }
+
+func TestNewDuration(t *testing.T) {
+	duration := NewDuration(1.5, Week)
+	if duration.ToHours() != 60 {
+		t.Fatalf("wrong hours")
+	}
+	checkParseCorrect(t, duration.String(), duration)
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"sort"
+	"strings"
+)
+
+// Validate checks the project built from Go code the same way ProjectFromString checks the parsed one
+func (p Project) Validate() error {
+	errors := &ProjectParseError{}
+	validateProject(p, errors)
+	for _, scenario := range p.Scenarios {
+		scenarioErrors := &ProjectParseError{}
+		validateProject(scenario.Project, scenarioErrors)
+		errors.addPrefixed("Scenario "+scenario.Name+": ", scenarioErrors)
+	}
+	if !errors.hasErrors() {
+		return nil
+	}
+	return errors
+}
+
+func validateProject(proj Project, errors *ProjectParseError) {
+	errors.pos = proj.directivesPos[directiveTimeUnit.name]
+	if _, known := timeUnit2Str[proj.TimeUnit]; !known {
+		errors.addErrorf("Unknown time_unit: %d", proj.TimeUnit)
+	}
+	errors.pos = proj.directivesPos[directiveCurrency.name]
+	if _, known := currency2Str[proj.Currency]; !known {
+		errors.addErrorf("Unknown currency: %d", proj.Currency)
+	}
+	errors.pos = proj.directivesPos[directiveAcceptancePercent.name]
+	if proj.AcceptancePercent < 0 || proj.AcceptancePercent > 100 {
+		errors.addErrorf("Wrong acceptance_percent: %v", proj.AcceptancePercent)
+	}
+	errors.pos = proj.directivesPos[directiveDesiredDuration.name]
+	if proj.DesiredDuration.ToHours() < 0 {
+		errors.addErrorf("Desired duration must be > 0: %s", proj.DesiredDuration)
+	}
+	errors.pos = proj.directivesPos[directiveRisks.name]
+	for _, risk := range RiskLabels(proj.Risks) {
+		if proj.Risks[risk] < 1 {
+			errors.addErrorf("Wrong risk value for %s: %v", risk, proj.Risks[risk])
+		}
+	}
+
+	for _, r := range proj.Team {
+		errors.pos = r.Pos
+		role, level, hasLevel := strings.Cut(r.Id, levelSeparator)
+		if role == "" || hasLevel && level == "" {
+			errors.addError("Wrong resource id: " + r.Id)
+		}
+		if r.Count < 0 {
+			errors.addErrorf("Team count must be >= 0 for %s: %d", r.Id, r.Count)
+		}
+		if r.Rate < 0 {
+			errors.addErrorf("Rate must be >= 0 for %s: %v", r.Id, r.Rate)
+		}
+		if r.Velocity < 0 {
+			errors.addErrorf("Velocity must be > 0 for %s: %v", r.Id, r.Velocity)
+		}
+	}
+	validateTeam(proj, errors)
+
+	for _, task := range proj.Tasks {
+		errors.pos = task.Pos
+		if task.Risk != "" {
+			if _, exists := proj.Risks[task.Risk]; !exists {
+				errors.addError("Wrong risks name: " + task.Risk)
+			}
+		}
+		for _, k := range sortedKeys(task.Work) {
+			if task.Work[k] < 0 {
+				errors.addErrorf("Effort should be >= 0 for task %s|%s for resource %s: %v", task.Category, task.Title, k, task.Work[k])
+			}
+			if r := proj.ResourceById(k); r == nil && !proj.hasRole(k) {
+				errors.addError("Wrong resource name in efforts: " + k)
+			} else if r != nil && r.Level() != "" {
+				// the efforts are shared by the levels of the role
+				errors.addErrorf("Effort for %s must be given for the role %s", k, r.Role())
+			}
+		}
+	}
+}
+
+func validateTeam(proj Project, errors *ProjectParseError) {
+	roles := map[string]float64{}
+	for _, role := range proj.WorkRoles() {
+		roles[role.Id] = 1
+	}
+	levelsOfRole := map[string][]Resource{}
+	ids := map[string]bool{}
+	for _, r := range proj.Team {
+		errors.pos = r.Pos
+		if ids[r.Id] {
+			errors.addError("Duplicating resource: " + r.Id)
+		}
+		ids[r.Id] = true
+		if r.Formula != "" {
+			if r.Level() != "" {
+				errors.addError("Derived resource can't have seniority level: " + r.Id)
+			}
+			if _, err := evalFormula(r.Formula, roles); err != nil {
+				errors.addErrorf("Wrong formula for %s: %s", r.Id, err)
+			}
+			continue
+		}
+		levelsOfRole[r.Role()] = append(levelsOfRole[r.Role()], r)
+	}
+	for _, workRole := range proj.WorkRoles() {
+		role := workRole.Id
+		levels := levelsOfRole[role]
+		hasLevels := false
+		for _, r := range levels {
+			hasLevels = hasLevels || r.Level() != ""
+		}
+		if !hasLevels {
+			continue
+		}
+		errors.pos = levels[0].Pos
+		capacity := 0.0
+		for _, r := range levels {
+			if r.Level() == "" {
+				errors.addErrorf("Resource %s can't be mixed with its seniority levels", role)
+				break
+			}
+			capacity += float64(r.Count) * r.velocity()
+		}
+		if capacity == 0 && proj.DesiredDuration == (Duration{}) {
+			errors.addErrorf("At least one seniority level of %s must have cnt > 0", role)
+		}
+	}
+}
+
+func sortedKeys(m map[string]float64) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
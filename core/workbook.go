@@ -0,0 +1,89 @@
+package core
+
+import (
+	"encoding/json"
+	"github.com/xuri/excelize/v2"
+	"io"
+)
+
+// workbook is the spreadsheet the model is generated into, .xlsx or .ods.
+// Cells and formulas are addressed the Excel way, the implementations translate them as needed.
+type workbook interface {
+	newSheet(name string)
+	newStyle(style *excelize.Style) (int, error)
+	setCellStyle(sheet, cell string, styleId int) error
+	setCellValue(sheet, cell string, val interface{}) error
+	setCellFormula(sheet, cell, formula string) error
+	mergeCell(sheet, hCell, vCell string) error
+	// addDropList restricts the cell to the values of the named range
+	addDropList(sheet, cell, name string) error
+	setDefinedName(name, refersTo string) error
+	getCols(sheet string) ([][]string, error)
+	setColWidth(sheet, col string, width float64) error
+	addChart(sheet, cell, format string) error
+	// addComment attaches the note shown on hover to the cell
+	addComment(sheet, cell, text string) error
+	// setRowOutlineLevel groups the 1-based row with the neighbour rows of the same or deeper level, 0 is not grouped
+	setRowOutlineLevel(sheet string, row int, level int) error
+	write(w io.Writer) error
+}
+
+type xlsxWorkbook struct {
+	f *excelize.File
+}
+
+func newXlsxWorkbook(sheet string) *xlsxWorkbook {
+	f := excelize.NewFile()
+	f.SetSheetName(f.GetSheetName(0), sheet)
+	return &xlsxWorkbook{f: f}
+}
+
+func (x *xlsxWorkbook) newSheet(name string) {
+	x.f.NewSheet(name)
+}
+func (x *xlsxWorkbook) newStyle(style *excelize.Style) (int, error) {
+	return x.f.NewStyle(style)
+}
+func (x *xlsxWorkbook) setCellStyle(sheet, cell string, styleId int) error {
+	return x.f.SetCellStyle(sheet, cell, cell, styleId)
+}
+func (x *xlsxWorkbook) setCellValue(sheet, cell string, val interface{}) error {
+	return x.f.SetCellValue(sheet, cell, val)
+}
+func (x *xlsxWorkbook) setCellFormula(sheet, cell, formula string) error {
+	return x.f.SetCellFormula(sheet, cell, formula)
+}
+func (x *xlsxWorkbook) mergeCell(sheet, hCell, vCell string) error {
+	return x.f.MergeCell(sheet, hCell, vCell)
+}
+func (x *xlsxWorkbook) addDropList(sheet, cell, name string) error {
+	dv := excelize.NewDataValidation(true)
+	dv.Sqref = cell + ":" + cell
+	dv.SetSqrefDropList(name)
+	return x.f.AddDataValidation(sheet, dv)
+}
+func (x *xlsxWorkbook) setDefinedName(name, refersTo string) error {
+	return x.f.SetDefinedName(&excelize.DefinedName{Name: name, RefersTo: refersTo})
+}
+func (x *xlsxWorkbook) getCols(sheet string) ([][]string, error) {
+	return x.f.GetCols(sheet)
+}
+func (x *xlsxWorkbook) setColWidth(sheet, col string, width float64) error {
+	return x.f.SetColWidth(sheet, col, col, width)
+}
+func (x *xlsxWorkbook) addChart(sheet, cell, format string) error {
+	return x.f.AddChart(sheet, cell, format)
+}
+func (x *xlsxWorkbook) setRowOutlineLevel(sheet string, row int, level int) error {
+	return x.f.SetRowOutlineLevel(sheet, row, uint8(level))
+}
+func (x *xlsxWorkbook) addComment(sheet, cell, text string) error {
+	format, err := json.Marshal(map[string]string{"author": "", "text": text})
+	if err != nil {
+		return err
+	}
+	return x.f.AddComment(sheet, cell, string(format))
+}
+func (x *xlsxWorkbook) write(w io.Writer) error {
+	return x.f.Write(w)
+}
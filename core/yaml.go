@@ -0,0 +1,501 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// keys of the YAML (and JSON) form of the project besides the directives, see proj_estimate1.yml
+const (
+	yamlTeam      = "team"
+	yamlTasks     = "tasks"
+	yamlScenarios = "scenarios"
+	yamlId        = "id"
+	yamlCategory  = "cat"
+	yamlTitle     = "title"
+	yamlRisk      = "risk"
+	yamlWork      = "work"
+	yamlExclude   = "exclude"
+)
+
+// yamlAliases are the alternative names of the properties of tasks and team members
+var yamlAliases = map[string]string{
+	"category": yamlCategory,
+	risksKey:   yamlRisk,
+	"count":    "cnt",
+}
+
+func yamlKey(key string) string {
+	if alias, exists := yamlAliases[key]; exists {
+		return alias
+	}
+	return key
+}
+
+func withYamlAliases(props map[string]string) map[string]string {
+	res := map[string]string{}
+	for k, v := range props {
+		res[yamlKey(k)] = v
+	}
+	return res
+}
+
+// ProjectFromYaml parses the project in the form of proj_estimate1.yml, the checks are the same as for ProjectFromString
+func ProjectFromYaml(projData string) (Project, error) {
+	projParsed, err := parseYaml(projData)
+	return projectWithScenarios(projParsed, withFileOnlyErrors(projParsed, err))
+}
+
+// ProjectFromJson parses the project in the same form as ProjectFromYaml
+func ProjectFromJson(projData string) (Project, error) {
+	if err := checkJson(projData); err != nil {
+		return Project{}, err
+	}
+	// JSON is YAML
+	return ProjectFromYaml(projData)
+}
+
+// the formats of ProjectFileOptions
+const (
+	formatYaml = "yaml"
+	formatJson = "json"
+)
+
+// checkJson reports the JSON syntax errors with their lines, the YAML parser would report them not that clear
+func checkJson(projData string) error {
+	var v interface{}
+	if err := json.Unmarshal([]byte(projData), &v); err != nil {
+		errors := &ProjectParseError{}
+		if syntaxError, ok := err.(*json.SyntaxError); ok {
+			errors.pos = Pos{Line: strings.Count(projData[:syntaxError.Offset], "\n") + 1}
+		}
+		errors.addError("Wrong JSON: " + err.Error())
+		return errors
+	}
+	return nil
+}
+
+func parseYaml(projData string) (projParsed, error) {
+	errors := &ProjectParseError{}
+	projParsed := projParsed{
+		directives:   map[string]directiveVals{},
+		tasksRecords: []taskRecord{},
+		sections:     map[string][]string{},
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(projData), &doc); err != nil {
+		errors.addError("Wrong YAML: " + err.Error())
+		return projParsed, errors
+	}
+	if len(doc.Content) == 0 {
+		return projParsed, nil
+	}
+	root := doc.Content[0]
+	if !yamlExpect(root, yaml.MappingNode, "project", errors) {
+		return projParsed, errors
+	}
+	forEachYamlPair(root, func(key string, keyNode, value *yaml.Node) {
+		errors.pos = yamlPos(keyNode)
+		switch key {
+		case yamlTeam:
+			projParsed.team = parseYamlTeam(value, errors)
+		case yamlTasks:
+			projParsed.tasksRecords = parseYamlTasks(value, "", errors)
+		case yamlScenarios:
+			if !yamlExpect(value, yaml.MappingNode, key, errors) {
+				return
+			}
+			forEachYamlPair(value, func(name string, nameNode, scenarioNode *yaml.Node) {
+				for _, scenario := range projParsed.scenarios {
+					if scenario.name == name {
+						errors.pos = yamlPos(nameNode)
+						errors.addError("Duplicating scenario: " + name)
+					}
+				}
+				projParsed.scenarios = append(projParsed.scenarios, parseYamlScenario(name, nameNode, scenarioNode, errors))
+			})
+		case assumptionsKey, exclusionsKey, notesKey:
+			projParsed.sections[key] = parseYamlList(value, key, errors)
+		default:
+			parseYamlDirective(key, keyNode, value, projParsed.directives, errors)
+		}
+	})
+	errors.pos = Pos{}
+	if !errors.hasErrors() {
+		return projParsed, nil
+	}
+	return projParsed, errors
+}
+
+func parseYamlDirective(key string, keyNode, value *yaml.Node, directiveValues map[string]directiveVals, errors *ProjectParseError) {
+	errors.pos = yamlPos(keyNode)
+	directive, found := directives[key]
+	if !found {
+		errors.addError("Unknown directive: " + key)
+		return
+	}
+	if directive.directiveType == DtSingleValue {
+		if yamlExpect(value, yaml.ScalarNode, key, errors) {
+			directiveValues[key] = directiveVals{directiveDef: directive, pos: yamlPos(keyNode), value: value.Value}
+		}
+	} else if directive.directiveType == DtKeyVal {
+		if yamlExpect(value, yaml.MappingNode, key, errors) {
+			directiveValues[key] = directiveVals{directiveDef: directive, pos: yamlPos(keyNode), values: yamlProps(value, errors)}
+		}
+	}
+}
+
+// parseYamlTeam accepts both the mapping of id to properties and the list of properties with id
+func parseYamlTeam(node *yaml.Node, errors *ProjectParseError) []resourceRecord {
+	var res []resourceRecord
+	if node.Kind == yaml.SequenceNode {
+		for _, item := range node.Content {
+			errors.pos = yamlPos(item)
+			if !yamlExpect(item, yaml.MappingNode, yamlTeam, errors) {
+				continue
+			}
+			props := withYamlAliases(yamlProps(item, errors))
+			id := props[yamlId]
+			delete(props, yamlId)
+			if id == "" {
+				errors.pos = yamlPos(item)
+				errors.addError("Team member should have id")
+			}
+			res = append(res, resourceRecord{pos: yamlPos(item), id: id, resourceProps: props})
+		}
+		return res
+	}
+	if !yamlExpect(node, yaml.MappingNode, yamlTeam, errors) {
+		return res
+	}
+	forEachYamlPair(node, func(id string, idNode, props *yaml.Node) {
+		record := resourceRecord{pos: yamlPos(idNode), id: id, resourceProps: map[string]string{}}
+		// a member without properties is null
+		if props.Tag != "!!null" && yamlExpect(props, yaml.MappingNode, id, errors) {
+			record.resourceProps = withYamlAliases(yamlProps(props, errors))
+		}
+		res = append(res, record)
+	})
+	return res
+}
+
+// parseYamlTasks reads the list of tasks, the items with nested tasks set the category for them,
+// the nested items with own category are in the subcategory of it
+func parseYamlTasks(node *yaml.Node, category string, errors *ProjectParseError) []taskRecord {
+	var res []taskRecord
+	if !yamlExpect(node, yaml.SequenceNode, yamlTasks, errors) {
+		return res
+	}
+	for _, item := range node.Content {
+		errors.pos = yamlPos(item)
+		if !yamlExpect(item, yaml.MappingNode, "task", errors) {
+			continue
+		}
+		record := taskRecord{pos: yamlPos(item), category: category, taskProps: map[string]string{}}
+		var nested *yaml.Node
+		forEachYamlPair(item, func(key string, keyNode, value *yaml.Node) {
+			errors.pos = yamlPos(keyNode)
+			key = yamlKey(key)
+			switch key {
+			case yamlTasks:
+				nested = value
+			case tagsKey:
+				if value.Kind == yaml.SequenceNode {
+					record.taskProps[key] = strings.Join(parseYamlList(value, key, errors), tagSeparator)
+				} else if yamlExpect(value, yaml.ScalarNode, key, errors) {
+					record.taskProps[key] = value.Value
+				}
+			case yamlWork:
+				if yamlExpect(value, yaml.MappingNode, key, errors) {
+					for resId, effort := range yamlProps(value, errors) {
+						record.taskProps[resId] = effort
+					}
+				}
+			default:
+				if !yamlExpect(value, yaml.ScalarNode, key, errors) {
+					return
+				}
+				switch key {
+				case yamlCategory:
+					// nested in the category of the enclosing item
+					if category != "" {
+						record.category = category + categorySeparator + value.Value
+					} else {
+						record.category = value.Value
+					}
+				case yamlTitle:
+					record.title = value.Value
+				case yamlRisk:
+					record.taskProps[risksKey] = value.Value
+				default:
+					record.taskProps[key] = value.Value
+				}
+			}
+		})
+		if nested != nil {
+			res = append(res, parseYamlTasks(nested, record.category, errors)...)
+			continue
+		}
+		if record.category == "" || record.title == "" {
+			errors.pos = record.pos
+			errors.addError("task should have cat and title")
+		}
+		res = append(res, record)
+	}
+	return res
+}
+
+// parseYamlList reads the list of texts, like the assumptions
+func parseYamlList(node *yaml.Node, what string, errors *ProjectParseError) []string {
+	var res []string
+	if !yamlExpect(node, yaml.SequenceNode, what, errors) {
+		return res
+	}
+	for _, item := range node.Content {
+		if yamlExpect(item, yaml.ScalarNode, what, errors) {
+			res = append(res, item.Value)
+		}
+	}
+	return res
+}
+
+func parseYamlScenario(name string, nameNode, node *yaml.Node, errors *ProjectParseError) scenarioRecord {
+	res := scenarioRecord{pos: yamlPos(nameNode), name: name, directives: map[string]directiveVals{}}
+	errors.pos = res.pos
+	if !yamlExpect(node, yaml.MappingNode, name, errors) {
+		return res
+	}
+	forEachYamlPair(node, func(key string, keyNode, value *yaml.Node) {
+		errors.pos = yamlPos(keyNode)
+		switch key {
+		case yamlTeam:
+			res.team = parseYamlTeam(value, errors)
+		case yamlTasks:
+			res.tasks = parseYamlTasks(value, "", errors)
+		case yamlExclude:
+			if !yamlExpect(value, yaml.SequenceNode, key, errors) {
+				return
+			}
+			for _, item := range value.Content {
+				errors.pos = yamlPos(item)
+				if !yamlExpect(item, yaml.ScalarNode, key, errors) {
+					continue
+				}
+				selectorParts := strings.SplitN(item.Value, "|", 2)
+				selector := taskSelector{category: strings.TrimSpace(selectorParts[0])}
+				if len(selectorParts) > 1 {
+					selector.title = strings.TrimSpace(selectorParts[1])
+				}
+				res.exclusions = append(res.exclusions, selector)
+			}
+		default:
+			parseYamlDirective(key, keyNode, value, res.directives, errors)
+		}
+	})
+	return res
+}
+
+func yamlPos(node *yaml.Node) Pos {
+	return Pos{Line: node.Line}
+}
+
+func yamlExpect(node *yaml.Node, kind yaml.Kind, what string, errors *ProjectParseError) bool {
+	if node.Kind == kind {
+		return true
+	}
+	kinds := map[yaml.Kind]string{yaml.ScalarNode: "a value", yaml.MappingNode: "a mapping", yaml.SequenceNode: "a list"}
+	errors.pos = yamlPos(node)
+	errors.addErrorf("%s should be %s", what, kinds[kind])
+	return false
+}
+
+func forEachYamlPair(node *yaml.Node, f func(key string, keyNode, value *yaml.Node)) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		f(node.Content[i].Value, node.Content[i], node.Content[i+1])
+	}
+}
+
+// yamlProps reads the mapping of values, like the properties of a team member
+func yamlProps(node *yaml.Node, errors *ProjectParseError) map[string]string {
+	res := map[string]string{}
+	forEachYamlPair(node, func(key string, keyNode, value *yaml.Node) {
+		if yamlExpect(value, yaml.ScalarNode, key, errors) {
+			res[key] = value.Value
+		}
+	})
+	return res
+}
+
+// ProjectToYaml gives the project in the form ProjectFromYaml understands. Scenarios are not included.
+func ProjectToYaml(p Project) (string, error) {
+	bytes, err := yaml.Marshal(projectToYamlNode(p))
+	return string(bytes), err
+}
+
+// ProjectToJson gives the project in the form ProjectFromJson understands. Scenarios are not included.
+func ProjectToJson(p Project) (string, error) {
+	var buf bytes.Buffer
+	if err := writeYamlNodeAsJson(&buf, projectToYamlNode(p)); err != nil {
+		return "", err
+	}
+	var res bytes.Buffer
+	if err := json.Indent(&res, buf.Bytes(), "", "  "); err != nil {
+		return "", err
+	}
+	return res.String(), nil
+}
+
+func projectToYamlNode(p Project) *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	addString := func(node *yaml.Node, key, value string) {
+		if value != "" {
+			node.Content = append(node.Content, yamlString(key), yamlString(value))
+		}
+	}
+	addNumber := func(node *yaml.Node, key string, value float64) {
+		node.Content = append(node.Content, yamlString(key), yamlNumber(value))
+	}
+	addString(root, directiveProject.name, p.Name)
+	addString(root, directiveAuthor.name, p.Author)
+	if p.Currency != CurrencyUnknown {
+		addString(root, directiveCurrency.name, strings.ToLower(p.Currency.String()))
+	}
+	if p.TimeUnit != TimeUnitUnknown {
+		addString(root, directiveTimeUnit.name, p.TimeUnit.String())
+	}
+	if p.AcceptancePercent != 0 {
+		addNumber(root, directiveAcceptancePercent.name, p.AcceptancePercent)
+	}
+	addString(root, directiveDesiredDuration.name, p.DesiredDuration.String())
+
+	risks := &yaml.Node{Kind: yaml.MappingNode}
+	for _, risk := range RiskLabels(p.Risks) {
+		addNumber(risks, risk, p.Risks[risk])
+	}
+	root.Content = append(root.Content, yamlString(directiveRisks.name), risks)
+
+	team := &yaml.Node{Kind: yaml.MappingNode}
+	for _, r := range p.Team {
+		props := &yaml.Node{Kind: yaml.MappingNode}
+		if r.Title != defaultTitle(r.Id) {
+			addString(props, yamlTitle, r.Title)
+		}
+		addNumber(props, "cnt", float64(r.Count))
+		addNumber(props, "rate", r.Rate)
+		if r.velocity() != 1 {
+			addNumber(props, "velocity", r.Velocity)
+		}
+		addString(props, "formula", r.Formula)
+		team.Content = append(team.Content, yamlString(r.Id), props)
+	}
+	root.Content = append(root.Content, yamlString(yamlTeam), team)
+
+	tasks := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, t := range p.Tasks {
+		task := &yaml.Node{Kind: yaml.MappingNode}
+		addString(task, yamlCategory, t.Category)
+		addString(task, yamlTitle, t.Title)
+		var resIds []string
+		for resId := range t.Work {
+			resIds = append(resIds, resId)
+		}
+		sort.Strings(resIds)
+		for _, resId := range resIds {
+			addNumber(task, resId, t.Work[resId])
+		}
+		addString(task, yamlRisk, t.Risk)
+		if t.Optional {
+			task.Content = append(task.Content, yamlString(optionalKey), &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: "true"})
+		}
+		if len(t.Tags) > 0 {
+			tags := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, tag := range t.Tags {
+				tags.Content = append(tags.Content, yamlString(tag))
+			}
+			task.Content = append(task.Content, yamlString(tagsKey), tags)
+		}
+		addString(task, noteKey, t.Notes)
+		addString(task, assumptionKey, t.Assumptions)
+		addString(task, outOfScopeKey, t.OutOfScope)
+		tasks.Content = append(tasks.Content, task)
+	}
+	root.Content = append(root.Content, yamlString(yamlTasks), tasks)
+
+	for _, section := range []struct {
+		key   string
+		items []string
+	}{{assumptionsKey, p.Assumptions}, {exclusionsKey, p.Exclusions}, {notesKey, p.Notes}} {
+		if len(section.items) == 0 {
+			continue
+		}
+		items := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range section.items {
+			items.Content = append(items.Content, yamlString(item))
+		}
+		root.Content = append(root.Content, yamlString(section.key), items)
+	}
+	return root
+}
+
+func yamlString(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+func yamlNumber(value float64) *yaml.Node {
+	// the tag matching the value is not written
+	tag := "!!float"
+	if value == math.Trunc(value) {
+		tag = "!!int"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+// writeYamlNodeAsJson writes the node keeping the order of the keys, that encoding/json would sort
+func writeYamlNodeAsJson(sb *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		sb.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			if err := writeYamlNodeAsJson(sb, node.Content[i]); err != nil {
+				return err
+			}
+			sb.WriteString(":")
+			if err := writeYamlNodeAsJson(sb, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("}")
+	case yaml.SequenceNode:
+		sb.WriteString("[")
+		for i, item := range node.Content {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			if err := writeYamlNodeAsJson(sb, item); err != nil {
+				return err
+			}
+		}
+		sb.WriteString("]")
+	case yaml.ScalarNode:
+		if node.Tag == "!!float" || node.Tag == "!!int" || node.Tag == "!!bool" {
+			sb.WriteString(node.Value)
+			return nil
+		}
+		bytes, err := json.Marshal(node.Value)
+		if err != nil {
+			return err
+		}
+		sb.Write(bytes)
+	default:
+		return fmt.Errorf("unexpected YAML node: %v", node.Kind)
+	}
+	return nil
+}
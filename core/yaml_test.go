@@ -0,0 +1,63 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProjectFromYaml(t *testing.T) {
+	data, err := os.ReadFile("../proj_estimate1.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := ProjectFromYaml(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(project.Team) != 4 || project.ResourceById("be").Count != 2 {
+		t.Fatalf("wrong team: %v", project.Team)
+	}
+	if project.Tasks[0].Category != "Initial" || project.Tasks[0].Work["fe"] != 5 {
+		t.Fatalf("nested tasks must inherit the category: %v", project.Tasks[0])
+	}
+}
+
+func TestProjectFromYamlErrors(t *testing.T) {
+	_, err := ProjectFromYaml(`
+acceptance_percent: 150
+team:
+  be:
+    rate: -5
+`)
+	var parseError *ProjectParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("must be ProjectParseError: %v", err)
+	}
+	lines := map[int]bool{}
+	for _, e := range parseError.Errors() {
+		lines[e.Pos.Line] = true
+	}
+	if !lines[2] || !lines[4] {
+		t.Fatalf("must point to the lines of the errors: %v", err)
+	}
+	_, err = ProjectFromJson("{\n\"team\": [,]}")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("must point to the line of the syntax error: %v", err)
+	}
+}
+
+func TestProjectToJson(t *testing.T) {
+	project := mustNoError(t, projData)
+	str, err := ProjectToJson(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ProjectFromJson(str)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, str)
+	}
+	assertFloat(t, "cost", project.Calculate().Cost, parsed.Calculate().Cost)
+	assertFloat(t, "duration", project.Calculate().DurationWithRisks, parsed.Calculate().DurationWithRisks)
+}
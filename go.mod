@@ -2,7 +2,10 @@ module estimatorium
 
 go 1.18
 
-require github.com/xuri/excelize/v2 v2.6.0
+require (
+	github.com/xuri/excelize/v2 v2.6.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826 // indirect
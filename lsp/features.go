@@ -0,0 +1,361 @@
+package lsp
+
+import (
+	"errors"
+	"estimatorium/core"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// position is 0-based, the character counts UTF-16 code units as the protocol says
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	Uri   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+const severityError = 1
+
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// completion item kinds
+const (
+	kindVariable = 6
+	kindKeyword  = 14
+	kindEnum     = 20
+)
+
+type completionItem struct {
+	Label      string `json:"label"`
+	Kind       int    `json:"kind"`
+	Detail     string `json:"detail,omitempty"`
+	InsertText string `json:"insertText,omitempty"`
+}
+
+type hoverResult struct {
+	Contents markupContent `json:"contents"`
+	Range    lspRange      `json:"range"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// section of the project text, mirrors the modes of the parser
+type section int
+
+const (
+	secDirectives section = iota
+	secTeam
+	secTasks
+	secScenario
+	secText // assumptions, exclusions or notes
+)
+
+const (
+	teamKeyword     = "team"
+	tasksKeyword    = "tasks"
+	scenarioKeyword = "scenario"
+	excludeKeyword  = "exclude"
+	includeKeyword  = "include"
+	risksKey        = "risks"
+	tagsKey         = "tags"
+	optionalKeyword = "optional"
+)
+
+var textKeywords = []string{"assumptions", "exclusions", "notes"}
+
+func isTextKeyword(word string) bool {
+	for _, keyword := range textKeywords {
+		if word == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+func sectionAt(lines []string, line int) section {
+	res := secDirectives
+	for _, l := range lines[:line] {
+		l = strings.TrimSpace(l)
+		fields := strings.Fields(l)
+		if l == tasksKeyword {
+			res = secTasks
+		} else if l == teamKeyword {
+			res = secTeam
+		} else if isTextKeyword(l) {
+			res = secText
+		} else if len(fields) > 0 && fields[0] == scenarioKeyword && pipes(l) == 0 {
+			res = secScenario
+		}
+	}
+	return res
+}
+
+// uriPath gives the path of the file:// document, "" for the other ones
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+func pathUri(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// loadProject parses the document along with the files it includes if it's a file
+func loadProject(path, text string) (core.Project, error) {
+	if path == "" {
+		return core.ProjectFromString(text)
+	}
+	project, _, err := core.ProjectFromFileText(path, text)
+	return project, err
+}
+
+// inDocument tells if the position is in the document rather than in a file it includes
+func inDocument(path string, pos core.Pos) bool {
+	return pos.File == "" || pos.File == path
+}
+
+func diagnostics(path, text string) []diagnostic {
+	res := []diagnostic{}
+	_, err := loadProject(path, text)
+	if err == nil {
+		return res
+	}
+	lines := strings.Split(text, "\n")
+	var parseError *core.ProjectParseError
+	if !errors.As(err, &parseError) {
+		return append(res, diagnostic{Range: lineRange(lines, 0), Severity: severityError, Source: "estimatorium", Message: err.Error()})
+	}
+	for _, e := range parseError.Errors() {
+		line := 0
+		message := e.Message
+		if !inDocument(path, e.Pos) {
+			// the problem of an included file is shown at the top with its position
+			message = e.String()
+		} else if e.Pos.Line > 0 {
+			line = e.Pos.Line - 1
+		}
+		res = append(res, diagnostic{Range: lineRange(lines, line), Severity: severityError, Source: "estimatorium", Message: message})
+	}
+	return res
+}
+
+// lineRange covers the line without the leading spaces
+func lineRange(lines []string, line int) lspRange {
+	if line >= len(lines) {
+		return lspRange{Start: position{Line: line}, End: position{Line: line}}
+	}
+	text := strings.TrimRight(lines[line], "\r")
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	return lspRange{
+		Start: position{Line: line, Character: indent},
+		End:   position{Line: line, Character: utf16Len(text)},
+	}
+}
+
+func completion(path, text string, pos position) []completionItem {
+	res := []completionItem{}
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) {
+		return res
+	}
+	line := lines[pos.Line]
+	prefix := line[:byteOffset(line, pos.Character)]
+	word := prefix[strings.LastIndexAny(prefix, " \t|")+1:]
+	fields := strings.Fields(prefix)
+	firstWord := len(fields) == 0 || len(fields) == 1 && word != ""
+	project, _ := loadProject(path, text)
+
+	switch sectionAt(lines, pos.Line) {
+	case secTasks:
+		if pipes(prefix) < 2 {
+			return res
+		}
+		if key, _, found := strings.Cut(word, "="); found {
+			if key == risksKey {
+				res = append(res, riskItems(project)...)
+			} else if key == tagsKey {
+				for _, tag := range project.Tags() {
+					res = append(res, completionItem{Label: tag, Kind: kindEnum})
+				}
+			}
+			return res
+		}
+		for _, role := range project.WorkRoles() {
+			res = append(res, completionItem{Label: role.Id, Kind: kindVariable, Detail: role.Title, InsertText: role.Id + "="})
+		}
+		return append(res,
+			completionItem{Label: risksKey, Kind: kindKeyword, InsertText: risksKey + "="},
+			completionItem{Label: tagsKey, Kind: kindKeyword, InsertText: tagsKey + "="},
+			completionItem{Label: optionalKeyword, Kind: kindKeyword, Detail: "not in the totals unless included"})
+	case secTeam:
+		if strings.Contains(word, "formula=") {
+			for _, r := range project.TeamExcludingDerived() {
+				res = append(res, completionItem{Label: r.Id, Kind: kindVariable, Detail: r.Title})
+			}
+		}
+		return res
+	case secText:
+		// free text, only the keywords ending the section
+		if firstWord {
+			for _, keyword := range append([]string{teamKeyword, tasksKeyword, scenarioKeyword}, textKeywords...) {
+				res = append(res, completionItem{Label: keyword, Kind: kindKeyword})
+			}
+		}
+		return res
+	default:
+		if firstWord {
+			for _, name := range core.DirectiveNames() {
+				res = append(res, completionItem{Label: name, Kind: kindKeyword})
+			}
+			keywords := append([]string{teamKeyword, tasksKeyword, scenarioKeyword, includeKeyword}, textKeywords...)
+			if sectionAt(lines, pos.Line) == secScenario {
+				keywords = []string{teamKeyword, excludeKeyword, scenarioKeyword}
+			}
+			for _, keyword := range keywords {
+				res = append(res, completionItem{Label: keyword, Kind: kindKeyword})
+			}
+		} else if fields[0] == teamKeyword && len(fields) <= 2 {
+			for _, r := range project.Team {
+				res = append(res, completionItem{Label: r.Id, Kind: kindVariable, Detail: r.Title})
+			}
+		}
+		return res
+	}
+}
+
+func riskItems(project core.Project) []completionItem {
+	var res []completionItem
+	for _, risk := range core.RiskLabels(project.Risks) {
+		res = append(res, completionItem{Label: risk, Kind: kindEnum, Detail: fmt.Sprintf("×%g", project.Risks[risk])})
+	}
+	return res
+}
+
+// hover shows the efforts and cost of the task on the line, nil if it's not a task
+func hover(path, text string, pos position) *hoverResult {
+	project, _ := loadProject(path, text)
+	for _, task := range project.Tasks {
+		if task.Pos.Line != pos.Line+1 || !inDocument(path, task.Pos) {
+			continue
+		}
+		calc := project.CalculateTask(task, project.Calculate())
+		risk := ""
+		if task.Risk != "" {
+			risk = fmt.Sprintf(" (%s ×%g)", task.Risk, project.RiskFactor(task.Risk))
+		}
+		value := fmt.Sprintf("**%s | %s**\n\nEfforts: %g %ss, with risks%s: %g %ss\n\nCost: %s, direct: %s",
+			task.Category, task.Title, calc.Efforts, project.TimeUnit, risk, calc.EffortsWithRisks, project.TimeUnit,
+			project.Currency.Format(calc.Cost), project.Currency.Format(calc.DirectCost))
+		return &hoverResult{
+			Contents: markupContent{Kind: "markdown", Value: value},
+			Range:    lineRange(strings.Split(text, "\n"), pos.Line),
+		}
+	}
+	return nil
+}
+
+// definition finds the team lines of the resource the task refers to under the cursor,
+// all the seniority levels for a role. The uri is empty for the lines of the document itself.
+func definition(path, text string, pos position) []location {
+	var res []location
+	lines := strings.Split(text, "\n")
+	if pos.Line >= len(lines) || sectionAt(lines, pos.Line) != secTasks {
+		return res
+	}
+	line := lines[pos.Line]
+	offset := byteOffset(line, pos.Character)
+	if pipes(line[:offset]) < 2 {
+		return res
+	}
+	start := strings.LastIndexAny(line[:offset], " \t|") + 1
+	end := len(line)
+	if i := strings.IndexAny(line[offset:], " \t|\r"); i >= 0 {
+		end = offset + i
+	}
+	key, _, _ := strings.Cut(line[start:end], "=")
+	project, _ := loadProject(path, text)
+	for _, r := range project.Team {
+		if (r.Id != key && r.Role() != key) || r.Pos.Line == 0 {
+			continue
+		}
+		if inDocument(path, r.Pos) {
+			res = append(res, location{Range: lineRange(lines, r.Pos.Line-1)})
+		} else {
+			start := position{Line: r.Pos.Line - 1}
+			res = append(res, location{Uri: pathUri(r.Pos.File), Range: lspRange{Start: start, End: start}})
+		}
+	}
+	return res
+}
+
+// pipes counts the cell separators of the task row, the escaped and quoted pipes are not
+func pipes(s string) int {
+	res := 0
+	quoted, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == '|' && !quoted:
+			res++
+		}
+	}
+	return res
+}
+
+// byteOffset converts the UTF-16 position in the line to the index in the string
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += utf16RuneLen(r)
+	}
+	return len(line)
+}
+
+func utf16Len(s string) int {
+	res := 0
+	for _, r := range s {
+		res += utf16RuneLen(r)
+	}
+	return res
+}
+
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,251 @@
+// Package lsp is the language server of the project text format, talking the Language Server Protocol
+// over stdio. It publishes the errors of core.ProjectFromFileText as diagnostics, completes directives,
+// resource ids and risks, shows the efforts and cost of a task on hover and goes from a resource in
+// a task to its team line.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// textDocumentSyncFull is the only sync kind supported, the client sends the whole text on every change
+const textDocumentSyncFull = 1
+
+type message struct {
+	JsonRpc string           `json:"jsonrpc"`
+	Id      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]string // uri -> text
+	shutdown bool
+}
+
+// Serve talks to the client until it asks to exit, the error tells the client exited without shutdown
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{in: bufio.NewReader(in), out: out, docs: map[string]string{}}
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			return errors.New("input closed without exit")
+		}
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			s.reply(nil, nil, &responseError{codeParseError, "wrong message"})
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		s.handle(msg)
+	}
+}
+
+// read returns the next message, nil if it's not JSON
+func (s *server) read() (*message, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("wrong Content-Length: %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, nil
+	}
+	return &msg, nil
+}
+
+func (s *server) write(msg message) {
+	msg.JsonRpc = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	_, _ = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *server) reply(id *json.RawMessage, result interface{}, err *responseError) {
+	if id == nil && err == nil {
+		return
+	}
+	if id == nil {
+		null := json.RawMessage("null")
+		id = &null
+	}
+	if result == nil && err == nil {
+		// result must be present in a successful response, even if null
+		result = json.RawMessage("null")
+	}
+	s.write(message{Id: id, Result: result, Error: err})
+}
+
+func (s *server) notify(method string, params interface{}) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		panic(err)
+	}
+	s.write(message{Method: method, Params: raw})
+}
+
+func (s *server) handle(msg *message) {
+	defer func() {
+		// a bug in the features must not take the editor's language support down
+		if r := recover(); r != nil {
+			s.reply(msg.Id, nil, &responseError{codeInternalError, fmt.Sprint(r)})
+		}
+	}()
+	var result interface{}
+	var err error
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   textDocumentSyncFull,
+				"completionProvider": map[string]interface{}{"triggerCharacters": []string{"=", " "}},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "estimatorium"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				Uri  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			s.update(params.TextDocument.Uri, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument   textDocumentIdentifier `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err = json.Unmarshal(msg.Params, &params); err == nil && len(params.ContentChanges) > 0 {
+			s.update(params.TextDocument.Uri, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument textDocumentIdentifier `json:"textDocument"`
+		}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			delete(s.docs, params.TextDocument.Uri)
+			s.publishDiagnostics(params.TextDocument.Uri, []diagnostic{})
+		}
+	case "textDocument/completion":
+		result, err = s.withPosition(msg.Params, func(path, text string, pos position) interface{} {
+			return completion(path, text, pos)
+		})
+	case "textDocument/hover":
+		result, err = s.withPosition(msg.Params, func(path, text string, pos position) interface{} {
+			if h := hover(path, text, pos); h != nil {
+				return h
+			}
+			return nil
+		})
+	case "textDocument/definition":
+		result, err = s.withPosition(msg.Params, func(path, text string, pos position) interface{} {
+			res := []location{}
+			for _, l := range definition(path, text, pos) {
+				if l.Uri == "" {
+					l.Uri = positionUri(msg.Params)
+				}
+				res = append(res, l)
+			}
+			return res
+		})
+	default:
+		if msg.Id != nil && !strings.HasPrefix(msg.Method, "$/") {
+			s.reply(msg.Id, nil, &responseError{codeMethodNotFound, "method not supported: " + msg.Method})
+		}
+		return
+	}
+	if err != nil {
+		s.reply(msg.Id, nil, &responseError{codeInvalidParams, err.Error()})
+		return
+	}
+	s.reply(msg.Id, result, nil)
+}
+
+func (s *server) update(uri, text string) {
+	s.docs[uri] = text
+	s.publishDiagnostics(uri, diagnostics(uriPath(uri), text))
+}
+
+func (s *server) publishDiagnostics(uri string, diagnostics []diagnostic) {
+	s.notify("textDocument/publishDiagnostics", struct {
+		Uri         string       `json:"uri"`
+		Diagnostics []diagnostic `json:"diagnostics"`
+	}{uri, diagnostics})
+}
+
+type textDocumentIdentifier struct {
+	Uri string `json:"uri"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+func positionUri(params json.RawMessage) string {
+	var p textDocumentPositionParams
+	_ = json.Unmarshal(params, &p)
+	return p.TextDocument.Uri
+}
+
+// withPosition calls f with the path and text of the document and the position the request is about
+func (s *server) withPosition(params json.RawMessage, f func(path, text string, pos position) interface{}) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	text, ok := s.docs[p.TextDocument.Uri]
+	if !ok {
+		return nil, errors.New("document is not open: " + p.TextDocument.Uri)
+	}
+	return f(uriPath(p.TextDocument.Uri), text, p.Position), nil
+}
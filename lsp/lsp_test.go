@@ -0,0 +1,147 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const projData = `time_unit day
+currency usd
+risks low=1.1 high=2
+
+team
+be cnt=1 rate=40
+fe.senior cnt=1 rate=50
+fe.junior cnt=1 rate=25
+qa cnt=1 rate=20 formula=be*0.3
+
+tasks
+API | Login | be=5 risks=high
+UI  | Form  | fe=3
+`
+
+func labels(items []completionItem) string {
+	var res []string
+	for _, item := range items {
+		res = append(res, item.Label)
+	}
+	return strings.Join(res, ",")
+}
+
+func TestCompletion(t *testing.T) {
+	if res := labels(completion("", projData, position{Line: 1, Character: 2})); !strings.Contains(res, "currency,desired_duration") || !strings.Contains(res, "tasks") {
+		t.Fatalf("must complete directives: %s", res)
+	}
+	if res := labels(completion("", projData, position{Line: 12, Character: 14})); res != "be,fe,risks,tags,optional" {
+		t.Fatalf("must complete work roles: %s", res)
+	}
+	if res := labels(completion("", projData, position{Line: 11, Character: 25})); res != "low,high" {
+		t.Fatalf("must complete risks: %s", res)
+	}
+	if res := labels(completion("", projData, position{Line: 11, Character: 6})); res != "" {
+		t.Fatalf("must not complete the title: %s", res)
+	}
+}
+
+func TestHover(t *testing.T) {
+	h := hover("", projData, position{Line: 11, Character: 3})
+	if h == nil || !strings.Contains(h.Contents.Value, "with risks (high ×2): 10 days") ||
+		!strings.Contains(h.Contents.Value, "Cost: $3,575, direct: $3,200") {
+		t.Fatalf("wrong hover: %v", h)
+	}
+	if h := hover("", projData, position{Line: 5, Character: 1}); h != nil {
+		t.Fatalf("must be only for tasks: %v", h)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	if res := definition("", projData, position{Line: 11, Character: 15}); len(res) != 1 || res[0].Range.Start.Line != 5 {
+		t.Fatalf("must go to be: %v", res)
+	}
+	if res := definition("", projData, position{Line: 12, Character: 16}); len(res) != 2 || res[1].Range.Start.Line != 7 {
+		t.Fatalf("must go to all levels of fe: %v", res)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	res := diagnostics("", "team\n  be rate=x\n")
+	if len(res) != 1 || res[0].Range.Start != (position{Line: 1, Character: 2}) || res[0].Range.End.Character != 11 {
+		t.Fatalf("wrong diagnostics: %v", res)
+	}
+	if res := diagnostics("", projData); len(res) != 0 {
+		t.Fatalf("must be valid: %v", res)
+	}
+}
+
+func TestUtf16(t *testing.T) {
+	line := "a😀b"
+	if byteOffset(line, 3) != 5 || utf16Len(line) != 4 {
+		t.Fatalf("wrong UTF-16 conversion")
+	}
+}
+
+func frame(method string, id int, params interface{}) string {
+	msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+	body, _ := json.Marshal(msg)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func readAll(t *testing.T, r io.Reader) []map[string]interface{} {
+	var res []map[string]interface{}
+	in := bufio.NewReader(r)
+	for {
+		header, err := textproto.NewReader(in).ReadMIMEHeader()
+		if err == io.EOF {
+			return res
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(in, body); err != nil {
+			t.Fatal(err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, msg)
+	}
+}
+
+func TestServe(t *testing.T) {
+	doc := map[string]interface{}{"uri": "file:///p.txt"}
+	in := frame("initialize", 1, map[string]interface{}{}) +
+		frame("textDocument/didOpen", 0, map[string]interface{}{"textDocument": map[string]interface{}{"uri": "file:///p.txt", "text": "currency wrong\n"}}) +
+		frame("textDocument/hover", 2, map[string]interface{}{"textDocument": doc, "position": position{}}) +
+		frame("unknown/method", 3, nil) +
+		frame("shutdown", 4, nil) +
+		frame("exit", 0, nil)
+	var out strings.Builder
+	if err := Serve(strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	msgs := readAll(t, strings.NewReader(out.String()))
+	if len(msgs) != 5 {
+		t.Fatalf("wrong number of messages: %s", out.String())
+	}
+	if msgs[1]["method"] != "textDocument/publishDiagnostics" || !strings.Contains(out.String(), "Unknown currency: wrong") {
+		t.Fatalf("must publish diagnostics: %v", msgs[1])
+	}
+	if result, ok := msgs[2]["result"]; !ok || result != nil {
+		t.Fatalf("hover outside of tasks must be null: %v", msgs[2])
+	}
+	if msgs[3]["error"].(map[string]interface{})["code"].(float64) != codeMethodNotFound {
+		t.Fatalf("must be method not found: %v", msgs[3])
+	}
+}
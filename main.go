@@ -1,43 +1,204 @@
 package main
 
 import (
+	"errors"
 	"estimatorium/core"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+const version = "0.0.1"
+
+// Exit codes
 const (
-	version = "0.0.1"
-	usage   = "usage: ./estimatorium proj.txt report.xlsx"
+	exitOk           = 0
+	exitProjectError = 1 // the project doesn't parse, is not valid or not formatted for fmt --check
+	exitUsage        = 2
+	exitIOError      = 3
 )
 
-func main() {
-	args := os.Args
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"generate", "write the spreadsheet model and other outputs, print the summary", runGenerate},
+		{"validate", "check the projects and print the errors", runValidate},
+		{"fmt", "lay out the project text the standard way", runFmt},
+		{"calc", "print the cost and duration, scenarios and sensitivity", runCalc},
+		{"export", "convert the project to YAML or JSON", runExport},
+		{"diff", "compare two versions of the estimate", runDiff},
+		{"init", "start a new project", runInit},
+		{"serve", "run the editor and the JSON API", runServe},
+		{"lsp", "run the language server over stdio for editors", runLsp},
+	}
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Estimatorium v%s\n\nusage: estimatorium <command> [flags] [args]\n\n", version)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(w, "\n`estimatorium <command> -h` shows the flags of the command. "+
+		"Input \"-\" is stdin, output \"-\" is stdout.\n"+
+		"Exit codes: %d ok, %d project errors, %d wrong usage, %d I/O errors.\n",
+		exitOk, exitProjectError, exitUsage, exitIOError)
+}
 
-	realArgs := args[1:]
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	if len(realArgs) == 0 || len(realArgs) == 1 && (realArgs[0] == "--help" || realArgs[0] == "-h") {
-		fmt.Printf("Estimatorium v%s\n%s\n", version, usage)
-		os.Exit(0)
-	} else if len(realArgs) == 0 || len(realArgs) == 1 && (realArgs[0] == "--version" || realArgs[0] == "-v") {
+// run dispatches the command line to the command and gives the exit code
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return exitOk
+	}
+	if args[0] == "version" || args[0] == "-v" || args[0] == "--version" {
 		fmt.Println(version)
-		os.Exit(0)
-	} else if len(realArgs) == 2 {
-		bytes, err := os.ReadFile(realArgs[0])
+		return exitOk
+	}
+	if err := loadRoles(); err != nil {
+		return exitCode(err)
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil && (strings.HasPrefix(args[0], "-") || strings.ContainsAny(args[0], "./\\")) {
+		// the form before the commands: estimatorium [--compat] [--watch] proj.txt report.xlsx
+		cmd = findCommand("generate")
+		args = append([]string{cmd.name}, args...)
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	return exitCode(cmd.run(args[1:]))
+}
+
+// rolesEnv points to the organization-wide role definitions, roles.yml of the config directory is used if not set
+const rolesEnv = "ESTIMATORIUM_ROLES"
+
+// loadRoles registers the role definitions so that all the projects can use their titles, rates and formulas
+func loadRoles() error {
+	path := os.Getenv(rolesEnv)
+	if path == "" {
+		configDir, err := os.UserConfigDir()
 		if err != nil {
-			panic(err)
+			return nil
 		}
-		projectStr := string(bytes)
-		project, err := core.ProjectFromString(projectStr)
-		if err != nil {
-			fmt.Printf("%v\n", err)
-			os.Exit(1)
+		path = filepath.Join(configDir, "estimatorium", "roles.yml")
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+	}
+	roles, err := core.RolesFromFile(path)
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return ioError{err}
+	} else if err != nil {
+		return err
+	}
+	core.RegisterRoles(roles)
+	return nil
+}
+
+func newFlagSet(name, synopsis, description string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: estimatorium %s [flags] %s\n%s\n", name, synopsis, description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses the flags wherever they are among the positional arguments and checks their number,
+// max < 0 for any number. flag.ErrHelp is returned as is for -h.
+func parseArgs(flags *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err == flag.ErrHelp {
+			return nil, err
+		} else if err != nil {
+			// the flag package has already printed the problem with the usage
+			return nil, usageError{}
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < min || max >= 0 && len(positional) > max {
+		flags.Usage()
+		return nil, usageError{}
+	}
+	return positional, nil
+}
+
+// usageError is the wrong command line, an empty message means it has been reported already
+type usageError struct {
+	message string
+}
+
+func (ue usageError) Error() string {
+	return ue.message
+}
+
+func usageErrorf(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// ioError is the failure to read the input or write the output, as opposed to the problems of the project
+type ioError struct {
+	err error
+}
+
+func (ie ioError) Error() string {
+	return ie.err.Error()
+}
+
+func (ie ioError) Unwrap() error {
+	return ie.err
+}
+
+func exitCode(err error) int {
+	var usage usageError
+	var ioErr ioError
+	switch {
+	case err == nil || err == flag.ErrHelp:
+		return exitOk
+	case errors.As(err, &usage):
+		if usage.message != "" {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
 		}
-		project.Calculate()
-		fmt.Println(project)
-		core.GenerateExcel(project, realArgs[1])
-	} else {
-		fmt.Printf("I don't understand...\n%s\n", usage)
-		os.Exit(1)
+		return exitUsage
+	case errors.As(err, &ioErr):
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitIOError
+	default:
+		// core.ProjectParseError or the project the model can't be generated for, like without tasks
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitProjectError
 	}
 }
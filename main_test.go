@@ -0,0 +1,214 @@
+package main
+
+import (
+	"errors"
+	"estimatorium/server"
+	"flag"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const cliProject = `project Shop
+currency usd
+time_unit day
+
+team
+be cnt=1 rate=40
+
+tasks
+API | Login | be=2
+`
+
+func TestExitCode(t *testing.T) {
+	for _, test := range []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOk},
+		{flag.ErrHelp, exitOk},
+		{usageError{}, exitUsage},
+		{fmt.Errorf("proj.txt: %w", usageErrorf("unknown input format: %s", "xml")), exitUsage},
+		{ioError{os.ErrNotExist}, exitIOError},
+		{fmt.Errorf("proj.txt:\n%w", ioError{os.ErrPermission}), exitIOError},
+		{errors.New("1 error(s) in proj.txt"), exitProjectError},
+	} {
+		if code := exitCode(test.err); code != test.expected {
+			t.Fatalf("%v must give %d, not %d", test.err, test.expected, code)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		min, max int
+		expected []string
+		err      error
+	}{
+		{[]string{"proj.txt", "-q", "report.xlsx"}, 2, -1, []string{"proj.txt", "report.xlsx"}, nil},
+		{[]string{"-format", "md", "proj.txt", "-", "-"}, 2, -1, []string{"proj.txt", "-", "-"}, nil},
+		{[]string{"proj.txt"}, 2, -1, nil, usageError{}},
+		{[]string{"old.txt", "new.txt", "other.txt"}, 2, 2, nil, usageError{}},
+		{[]string{"proj.txt", "-unknown"}, 1, 1, nil, usageError{}},
+		{[]string{"proj.txt", "-h"}, 1, 1, nil, flag.ErrHelp},
+	} {
+		flags := newFlagSet("test", "args", "Test.")
+		flags.SetOutput(io.Discard)
+		flags.Bool("q", false, "quiet")
+		flags.String("format", "", "format")
+		positional, err := parseArgs(flags, test.args, test.min, test.max)
+		if err != test.err || !reflect.DeepEqual(positional, test.expected) {
+			t.Fatalf("%v: wrong %v %v", test.args, positional, err)
+		}
+	}
+}
+
+func TestInputOutputFormat(t *testing.T) {
+	for _, test := range []struct{ path, format, expected string }{
+		{"proj.yml", "", server.FormatYaml},
+		{"proj.YAML", "", server.FormatYaml},
+		{"proj.json", "", server.FormatJson},
+		{"proj.txt", "", server.FormatDsl},
+		{stdio, "", server.FormatDsl},
+		{"proj.json", server.FormatDsl, server.FormatDsl},
+	} {
+		if format := inputFormat(test.path, test.format); format != test.expected {
+			t.Fatalf("wrong input format of %s: %s", test.path, format)
+		}
+	}
+	for _, test := range []struct{ path, format, expected string }{
+		{"report.xlsx", "md", "xlsx"},
+		{"Report.ODS", "", "ods"},
+		{"report.tar.gz", "", "gz"},
+		{"report", "xlsx", ""},
+		{stdio, "md", "md"},
+	} {
+		if format := outputFormat(test.path, test.format); format != test.expected {
+			t.Fatalf("wrong output format of %s: %s", test.path, format)
+		}
+	}
+}
+
+// runWithStdio runs the command line with the text as stdin, it gives the exit code and what is printed to stdout,
+// stderr is dropped
+func runWithStdio(t *testing.T, stdin string, args ...string) (int, string) {
+	dir := t.TempDir()
+	inPath := filepath.Join(dir, "stdin")
+	if err := os.WriteFile(inPath, []byte(stdin), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in, err := os.Open(inPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	out, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	errOut, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer errOut.Close()
+	stdin0, stdout0, stderr0 := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = in, out, errOut
+	defer func() {
+		os.Stdin, os.Stdout, os.Stderr = stdin0, stdout0, stderr0
+	}()
+	code := run(args)
+	printed, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(printed)
+}
+
+func TestRun(t *testing.T) {
+	// no roles.yml of the user
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(rolesEnv, "")
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	for name, text := range map[string]string{
+		"proj.txt":  cliProject,
+		"wrong.txt": "team\nbe cnt=x\n",
+		"ugly.txt":  "team\nbe   cnt=1 rate=40\ntasks\nAPI|Login|be=2\n",
+	} {
+		if err := os.WriteFile(path(name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		args     []string
+		stdin    string
+		code     int
+		printed  string   // a part of stdout
+		produced []string // the files written
+	}{
+		{args: nil, code: exitOk, printed: "usage: estimatorium"},
+		{args: []string{"-v"}, code: exitOk, printed: version},
+		{args: []string{"unknown"}, code: exitUsage},
+		{args: []string{"validate", path("proj.txt")}, code: exitOk, printed: "proj.txt: ok"},
+		{args: []string{"validate", path("proj.txt"), path("wrong.txt")}, code: exitProjectError, printed: "wrong.txt:2:"},
+		{args: []string{"validate", path("missing.txt")}, code: exitIOError},
+		{args: []string{"validate"}, code: exitUsage},
+		{args: []string{"calc", path("proj.txt")}, code: exitOk, printed: "Shop: $640"},
+		{args: []string{"calc", "-range", "0", path("proj.txt")}, code: exitUsage},
+		{args: []string{"generate", "-q", path("proj.txt"), path("report.xlsx"), path("report.md"), path("proj.json")},
+			code: exitOk, produced: []string{"report.xlsx", "report.md", "proj.json"}},
+		{args: []string{"generate", path("proj.txt"), path("report.txt")}, code: exitUsage},
+		{args: []string{"generate", "-q", path("proj.txt"), path("missing/report.xlsx")}, code: exitIOError},
+		{args: []string{"generate", "-q", path("wrong.txt"), path("wrong.xlsx")}, code: exitProjectError},
+		// the form before the commands
+		{args: []string{path("proj.txt"), path("legacy.xlsx")}, code: exitOk, printed: "Shop: $640", produced: []string{"legacy.xlsx"}},
+		{args: []string{"--compat", "-q", path("proj.txt"), path("compat.xlsx")}, code: exitOk, produced: []string{"compat.xlsx"}},
+		// stdin and stdout
+		{args: []string{"generate", "-q", "-format", "md", stdio, stdio}, stdin: cliProject, code: exitOk, printed: "# Shop"},
+		{args: []string{"calc", "-input-format", "yaml", stdio}, stdin: "currency: usd\ntime_unit: day\nteam:\n  be: {cnt: 1, rate: 40}\ntasks:\n  - {cat: API, title: Login, be: 1}\n",
+			code: exitOk, printed: "Project: $320"},
+		{args: []string{"export", "-to", "json", path("proj.txt")}, code: exitOk, printed: `"title": "Login"`},
+		{args: []string{"fmt", "--check", path("proj.txt"), path("ugly.txt")}, code: exitProjectError, printed: "ugly.txt"},
+	} {
+		code, printed := runWithStdio(t, test.stdin, test.args...)
+		if code != test.code || !strings.Contains(printed, test.printed) {
+			t.Fatalf("%v: exit code %d, printed:\n%s", test.args, code, printed)
+		}
+		for _, name := range test.produced {
+			if info, err := os.Stat(path(name)); err != nil || info.Size() == 0 {
+				t.Fatalf("%v: %s is not written", test.args, name)
+			}
+		}
+	}
+	if _, err := os.Stat(path("wrong.xlsx")); err == nil {
+		t.Fatalf("the project with errors must not leave the output")
+	}
+}
+
+func TestGenerateForDesiredDuration(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "report.xlsx")
+	if code, printed := runWithStdio(t, "", "generate", "proj_estimate3_dd.txt", output); code != exitOk || !strings.Contains(printed, "team of 7") {
+		t.Fatalf("exit code %d, printed:\n%s", code, printed)
+	}
+	f, err := excelize.OpenFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the Team column of the costs table, the same team as the summary
+	for cell, expected := range map[string]string{"A13": "Blockchain", "E13": "1", "A14": "Back dev", "E14": "3", "E15": "1", "E16": "1", "E17": "1"} {
+		if value, _ := f.GetCellValue("Sheet1", cell); value != expected {
+			t.Fatalf("wrong %s: %s", cell, value)
+		}
+	}
+}
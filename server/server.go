@@ -0,0 +1,340 @@
+// Package server provides the JSON API over the estimatorium core:
+//
+//	POST /api/parse      the project as JSON, in the form core.ProjectFromJson understands
+//	POST /api/validate   the list of errors with their positions
+//	POST /api/calculate  the cost and duration of the project and its scenarios
+//	POST /api/xlsx       the model to download, ?compat=true for core.ExcelOptions.Compatible
+//	POST /api/ods        the same in OpenDocument format
+//
+// The root serves the editor, a page to write the project in the text format with live validation
+// and the totals. It's embedded in the binary and uses nothing but the API above.
+//
+// The project goes in the request body in the text format, YAML or JSON. The format is taken from
+// the "format" query parameter (dsl, yaml, json) or from the Content-Type, the text format by default.
+package server
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"estimatorium/core"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatDsl  = "dsl"
+	FormatYaml = "yaml"
+	FormatJson = "json"
+)
+
+const (
+	maxProjectSize  = 1 << 20
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	odsContentType  = "application/vnd.oasis.opendocument.spreadsheet"
+)
+
+//go:embed editor
+var editorFiles embed.FS
+
+// NewHandler returns the handler of the editor and the API, to be mounted at the root
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	editor, err := fs.Sub(editorFiles, "editor")
+	if err != nil {
+		panic(err)
+	}
+	mux.Handle("/", http.FileServer(http.FS(editor)))
+	mux.HandleFunc("/api/parse", withProject(handleParse))
+	mux.HandleFunc("/api/validate", handleValidate)
+	mux.HandleFunc("/api/calculate", withProject(handleCalculate))
+	mux.HandleFunc("/api/xlsx", withProject(handleWorkbook(core.GenerateExcelTo, xlsxContentType, "xlsx")))
+	mux.HandleFunc("/api/ods", withProject(handleWorkbook(core.GenerateOdsTo, odsContentType, "ods")))
+	return mux
+}
+
+func ListenAndServe(addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           NewHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server.ListenAndServe()
+}
+
+type errorResponse struct {
+	Errors []projectError `json:"errors"`
+}
+
+type projectError struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+type validateResponse struct {
+	Valid  bool           `json:"valid"`
+	Errors []projectError `json:"errors"`
+}
+
+// requestError is the problem of the request itself, not of the project in it
+type requestError struct {
+	status  int
+	message string
+}
+
+func (re requestError) Error() string {
+	return re.message
+}
+
+// readProject parses the project of the request, the error is either requestError or the errors of the project
+func readProject(w http.ResponseWriter, r *http.Request) (core.Project, error) {
+	if r.Method != http.MethodPost {
+		return core.Project{}, requestError{http.StatusMethodNotAllowed, "use POST"}
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProjectSize))
+	if err != nil {
+		return core.Project{}, requestError{http.StatusRequestEntityTooLarge, err.Error()}
+	}
+	format, err := requestFormat(r)
+	if err != nil {
+		return core.Project{}, err
+	}
+	switch format {
+	case FormatYaml:
+		return core.ProjectFromYaml(string(body))
+	case FormatJson:
+		return core.ProjectFromJson(string(body))
+	default:
+		return core.ProjectFromString(string(body))
+	}
+}
+
+func requestFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format != FormatDsl && format != FormatYaml && format != FormatJson {
+			return "", requestError{http.StatusBadRequest, "unknown format: " + format}
+		}
+		return format, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		return FormatJson, nil
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return FormatYaml, nil
+	default:
+		return FormatDsl, nil
+	}
+}
+
+// withProject passes the valid project of the request to the handler, or responds with the errors
+func withProject(handler func(w http.ResponseWriter, r *http.Request, project core.Project)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		project, err := readProject(w, r)
+		var reqErr requestError
+		if errors.As(err, &reqErr) {
+			writeJson(w, reqErr.status, errorResponse{Errors: []projectError{{Message: reqErr.message}}})
+			return
+		}
+		if err != nil {
+			writeJson(w, http.StatusUnprocessableEntity, errorResponse{Errors: projectErrors(err)})
+			return
+		}
+		handler(w, r, project)
+	}
+}
+
+func projectErrors(err error) []projectError {
+	res := []projectError{}
+	var parseError *core.ProjectParseError
+	if !errors.As(err, &parseError) {
+		return append(res, projectError{Message: err.Error()})
+	}
+	for _, e := range parseError.Errors() {
+		res = append(res, projectError{Line: e.Pos.Line, Message: e.Message})
+	}
+	return res
+}
+
+func handleValidate(w http.ResponseWriter, r *http.Request) {
+	_, err := readProject(w, r)
+	var reqErr requestError
+	if errors.As(err, &reqErr) {
+		writeJson(w, reqErr.status, errorResponse{Errors: []projectError{{Message: reqErr.message}}})
+		return
+	}
+	res := validateResponse{Valid: err == nil, Errors: []projectError{}}
+	if err != nil {
+		res.Errors = projectErrors(err)
+	}
+	writeJson(w, http.StatusOK, res)
+}
+
+func handleParse(w http.ResponseWriter, r *http.Request, project core.Project) {
+	projectJson, err := core.ProjectToJson(project)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, errorResponse{Errors: projectErrors(err)})
+		return
+	}
+	writeJson(w, http.StatusOK, struct {
+		Project json.RawMessage `json:"project"`
+	}{json.RawMessage(projectJson)})
+}
+
+func handleCalculate(w http.ResponseWriter, r *http.Request, project core.Project) {
+	var res []scenarioResponse
+	for _, scenario := range project.CalculateScenarios() {
+		res = append(res, newScenarioResponse(scenario))
+	}
+	writeJson(w, http.StatusOK, struct {
+		Scenarios []scenarioResponse `json:"scenarios"`
+	}{res})
+}
+
+func handleWorkbook(generate func(w io.Writer, project core.Project, opts core.ExcelOptions) error,
+	contentType, extension string) func(w http.ResponseWriter, r *http.Request, project core.Project) {
+	return func(w http.ResponseWriter, r *http.Request, project core.Project) {
+		compat, _ := strconv.ParseBool(r.URL.Query().Get("compat"))
+		var buf bytes.Buffer
+		if err := generate(&buf, project, core.ExcelOptions{Compatible: compat}); err != nil {
+			writeJson(w, http.StatusUnprocessableEntity, errorResponse{Errors: projectErrors(err)})
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName(project, extension)))
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+var unsafeFileNameRe = regexp.MustCompile(`[^\w.-]+`)
+
+func fileName(project core.Project, extension string) string {
+	name := unsafeFileNameRe.ReplaceAllString(project.Name, "_")
+	if name == "" || name == "_" {
+		name = "estimate"
+	}
+	return name + "." + extension
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// number is written as null when infinite, like the duration of a role without team members
+type number float64
+
+func (n number) MarshalJSON() ([]byte, error) {
+	if math.IsInf(float64(n), 0) || math.IsNaN(float64(n)) {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.FormatFloat(float64(n), 'f', -1, 64)), nil
+}
+
+type scenarioResponse struct {
+	Name              string               `json:"name"`
+	Efforts           number               `json:"efforts"`
+	EffortsWithRisks  number               `json:"efforts_with_risks"`
+	Cost              number               `json:"cost"`
+	Duration          number               `json:"duration_months"`
+	DurationWithRisks number               `json:"duration_with_risks_months"`
+	Resources         []resourceResponse   `json:"resources"`
+	Roles             []roleResponse       `json:"roles"`
+	Categories        []categoryResponse   `json:"categories"`
+	Team              []teamMemberResponse `json:"team"`
+}
+
+type teamMemberResponse struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+	Count int    `json:"cnt"`
+	Rate  number `json:"rate"`
+}
+
+type resourceResponse struct {
+	Id               string `json:"id"`
+	Title            string `json:"title"`
+	Efforts          number `json:"efforts"`
+	EffortsWithRisks number `json:"efforts_with_risks"`
+	Cost             number `json:"cost"`
+}
+
+type roleResponse struct {
+	Id                string `json:"id"`
+	Title             string `json:"title"`
+	Efforts           number `json:"efforts"`
+	EffortsWithRisks  number `json:"efforts_with_risks"`
+	Cost              number `json:"cost"`
+	BlendedRate       number `json:"blended_rate"`
+	Duration          number `json:"duration_months"`
+	DurationWithRisks number `json:"duration_with_risks_months"`
+}
+
+type categoryResponse struct {
+	Category         string `json:"category"`
+	Depth            int    `json:"depth"` // 1 for the top level, the nested categories follow their parent
+	Efforts          number `json:"efforts"`
+	EffortsWithRisks number `json:"efforts_with_risks"`
+	DirectCost       number `json:"direct_cost"`
+	Cost             number `json:"cost"`
+}
+
+func newScenarioResponse(scenario core.ScenarioResult) scenarioResponse {
+	result := scenario.Result
+	res := scenarioResponse{
+		Name:              scenario.Name,
+		Efforts:           number(result.Efforts),
+		EffortsWithRisks:  number(result.EffortsWithRisks),
+		Cost:              number(result.Cost),
+		Duration:          number(result.Duration),
+		DurationWithRisks: number(result.DurationWithRisks),
+		Resources:         []resourceResponse{},
+		Roles:             []roleResponse{},
+		Categories:        []categoryResponse{},
+		Team:              []teamMemberResponse{},
+	}
+	for _, r := range result.Team {
+		res.Team = append(res.Team, teamMemberResponse{Id: r.Id, Title: r.Title, Count: r.Count, Rate: number(r.Rate)})
+	}
+	for _, r := range result.Resources {
+		res.Resources = append(res.Resources, resourceResponse{
+			Id:               r.Id,
+			Title:            r.Title,
+			Efforts:          number(r.Efforts),
+			EffortsWithRisks: number(r.EffortsWithRisks),
+			Cost:             number(r.Cost),
+		})
+	}
+	for _, r := range result.Roles {
+		res.Roles = append(res.Roles, roleResponse{
+			Id:                r.Role.Id,
+			Title:             r.Role.Title,
+			Efforts:           number(r.Efforts),
+			EffortsWithRisks:  number(r.EffortsWithRisks),
+			Cost:              number(r.Cost),
+			BlendedRate:       number(r.BlendedRate),
+			Duration:          number(r.Duration),
+			DurationWithRisks: number(r.DurationWithRisks),
+		})
+	}
+	for _, c := range result.Categories {
+		res.Categories = append(res.Categories, categoryResponse{
+			Category:         c.Category,
+			Depth:            c.Depth,
+			Efforts:          number(c.TotalEfforts()),
+			EffortsWithRisks: number(c.TotalEffortsWithRisks()),
+			DirectCost:       number(c.DirectCost),
+			Cost:             number(c.Cost),
+		})
+	}
+	return res
+}
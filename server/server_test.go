@@ -0,0 +1,149 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/xuri/excelize/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const projData = `
+project Shop
+time_unit day
+currency usd
+
+team
+be cnt=2 rate=40
+fe cnt=1 rate=30
+
+tasks
+API | Login      | be=3 risks=high
+UI  | Login form | fe=2
+`
+
+func post(t *testing.T, url, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	NewHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestValidate(t *testing.T) {
+	rec := post(t, "/api/validate", "", "team\nbe rate=40\n\nacceptance_percent 150\n")
+	var res validateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || res.Valid || len(res.Errors) != 1 || res.Errors[0].Line != 4 {
+		t.Fatalf("wrong response %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	rec := post(t, "/api/calculate", "text/plain", projData)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("wrong response %d: %s", rec.Code, rec.Body)
+	}
+	var res struct {
+		Scenarios []struct {
+			Cost float64 `json:"cost"`
+		} `json:"scenarios"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Scenarios) != 1 || res.Scenarios[0].Cost <= 0 {
+		t.Fatalf("wrong result: %s", rec.Body)
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	rec := post(t, "/api/parse", "", projData)
+	var res struct {
+		Project json.RawMessage `json:"project"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	rec = post(t, "/api/validate?format=json", "", string(res.Project))
+	if !strings.Contains(rec.Body.String(), `"valid":true`) {
+		t.Fatalf("parsed project must be valid: %s\n%s", rec.Body, res.Project)
+	}
+}
+
+func TestWorkbook(t *testing.T) {
+	rec := post(t, "/api/xlsx", "", projData)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != xlsxContentType {
+		t.Fatalf("wrong response %d: %s", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), `filename="Shop.xlsx"`) {
+		t.Fatalf("wrong file name: %s", rec.Header().Get("Content-Disposition"))
+	}
+	if !strings.HasPrefix(rec.Body.String(), "PK") {
+		t.Fatalf("must be zip")
+	}
+}
+
+func TestWorkbookForDesiredDuration(t *testing.T) {
+	rec := post(t, "/api/xlsx", "", "time_unit day\ncurrency usd\ndesired_duration 1week\nteam\nbe rate=40\ntasks\nAPI | Login | be=10\n")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("wrong response %d: %s", rec.Code, rec.Body)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the team of the costs table is sized as /api/calculate does it
+	teamColZ := -1
+	for _, row := range rows {
+		for colZ, cell := range row {
+			if cell == "Team" {
+				teamColZ = colZ
+			}
+		}
+		if teamColZ >= 0 && len(row) > teamColZ && row[0] == "Back dev" {
+			if row[teamColZ] != "2" {
+				t.Fatalf("wrong team: %v", row)
+			}
+			return
+		}
+	}
+	t.Fatalf("no team in %v", rows)
+}
+
+func TestRequestErrors(t *testing.T) {
+	if rec := post(t, "/api/calculate", "", "team\nbe rate=-1\n"); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid project must be 422, got %d", rec.Code)
+	}
+	if rec := post(t, "/api/calculate?format=xml", "", projData); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown format must be 400, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/calculate", nil)
+	rec := httptest.NewRecorder()
+	NewHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET must be 405, got %d", rec.Code)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	NewHandler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/validate") {
+		t.Fatalf("must serve the editor, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "https://") {
+		t.Fatalf("editor must not depend on the network")
+	}
+}
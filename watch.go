@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	watchInterval = 200 * time.Millisecond
+	// watchDebounce is how long the files must stay unchanged before the run, editors often save in several writes
+	watchDebounce = 300 * time.Millisecond
+)
+
+// fileState tells the file has changed, the zero value is for a missing file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFiles(files []string) map[string]fileState {
+	res := map[string]fileState{}
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			res[file] = fileState{info.ModTime(), info.Size()}
+		} else {
+			res[file] = fileState{}
+		}
+	}
+	return res
+}
+
+func sameStates(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, state := range a {
+		if other, ok := b[file]; !ok || !state.modTime.Equal(other.modTime) || state.size != other.size {
+			return false
+		}
+	}
+	return true
+}
+
+// watchFiles calls run and then again each time any of the files it returns changes, until stop is closed,
+// never for nil. The files are polled so that it works the same on all platforms and network drives.
+func watchFiles(run func() []string, stop <-chan struct{}) {
+	for {
+		files := run()
+		states := statFiles(files)
+		fmt.Printf("Watching %d file(s) for changes, Ctrl+C to stop\n", len(files))
+		var changedAt time.Time
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(watchInterval):
+			}
+			current := statFiles(files)
+			if !sameStates(states, current) {
+				states = current
+				changedAt = time.Now()
+			} else if !changedAt.IsZero() && time.Since(changedAt) >= watchDebounce {
+				break
+			}
+		}
+		fmt.Printf("\n%s Changed, regenerating\n", time.Now().Format("15:04:05"))
+	}
+}
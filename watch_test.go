@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	write := func(name, text string) {
+		if err := os.WriteFile(path(name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("proj.txt", "currency usd\ntime_unit day\nrate_card rates.yml\ninclude tasks.txt\nteam\nbe cnt=1\n")
+	write("tasks.txt", "tasks\nAPI | Login | be=1\n")
+	write("rates.yml", "valid_from: 2024-01-01\nroles:\n  be:\n    rates: {usd: 40}\n")
+
+	in := addInputFlags(flag.NewFlagSet("test", flag.ContinueOnError))
+	costs := make(chan float64, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watchFiles(func() []string {
+			project, files, err := readProject(path("proj.txt"), in)
+			if err != nil {
+				t.Error(err)
+			}
+			costs <- project.Calculate().Cost
+			return files
+		}, stop)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	regenerated := func(expected float64) {
+		select {
+		case cost := <-costs:
+			if cost != expected {
+				t.Fatalf("wrong cost %v, must be %v", cost, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("not regenerated for the cost %v", expected)
+		}
+	}
+	regenerated(320)
+
+	// the editors save in several writes, the run is after the last one even if the polls see them apart
+	write("tasks.txt", "tasks\nAPI | Login | be=1\nAPI | Logout | be=2\n")
+	time.Sleep(watchInterval + watchInterval/4)
+	write("tasks.txt", "tasks\nAPI | Login | be=2\nAPI | Logout | be=2\n")
+	regenerated(1280)
+
+	write("rates.yml", "valid_from: 2024-01-01\nroles:\n  be:\n    rates: {usd: 50}\n")
+	regenerated(1600)
+
+	select {
+	case cost := <-costs:
+		t.Fatalf("regenerated without changes: %v", cost)
+	case <-time.After(watchInterval + watchDebounce + watchInterval):
+	}
+}